@@ -0,0 +1,132 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"goapp/constants"
+	"goapp/lua"
+	"goapp/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+var ErrReleaseUnsupported = errors.New("the limiter does not hand out leases")
+
+// Releaser is implemented by the limiters that cap in-flight work, every allowed request holds a lease until it is released or expires
+type Releaser interface {
+	Release(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier, leaseId string) (bool, error)
+}
+
+// ReleaseLease gives the lease back to the limiter, reporting false when the lease is unknown or already expired
+func ReleaseLease(ctx context.Context, limiter RateLimiter, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier, leaseId string) (bool, error) {
+	releaser, ok := limiter.(Releaser)
+	if !ok {
+		return false, ErrReleaseUnsupported
+	}
+	return releaser.Release(ctx, rdb, cb, log, scope, identifier, leaseId)
+}
+
+func leaseTTL(windowStr string, log zerolog.Logger) time.Duration {
+	if windowStr == "" {
+		return constants.DefaultLeaseTTL
+	}
+
+	ttl, err := time.ParseDuration(windowStr)
+	if err != nil || ttl <= 0 {
+		log.Error().Err(err).Str("window", windowStr).Msg("Invalid lease ttl, using the default")
+		return constants.DefaultLeaseTTL
+	}
+	return ttl
+}
+
+type ConcurrencyLimiterRedis struct {
+	capacity int64
+	leaseTTL time.Duration
+}
+
+// NewConcurrencyLimiter caps the in-flight requests at capacity, the window is the lease ttl
+func NewConcurrencyLimiter(windowStr string, capacity int64, log zerolog.Logger) *ConcurrencyLimiterRedis {
+	return &ConcurrencyLimiterRedis{
+		capacity: capacity,
+		leaseTTL: leaseTTL(windowStr, log),
+	}
+}
+
+func concurrencyKeys(scope, identifier string) []string {
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmConcurrency, scope, identifier)
+	return []string{redisKey, redisKey + ":slots"}
+}
+
+func (cl *ConcurrencyLimiterRedis) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	now := time.Now().UnixMilli()
+	leaseId := uuid.NewString()
+
+	acquireScript := redis.NewScript(lua.GetConcurrencyAcquireScript())
+
+	results, err := cb.Cb.Execute(func() (any, error) {
+		results, err := acquireScript.Run(ctx, rdb, concurrencyKeys(scope, identifier), cl.capacity, cl.leaseTTL.Milliseconds(), now, cost, leaseId).Result()
+		return results, err
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Error calling the concurrency acquire script, rejecting the request")
+		return &models.LimiterResponse{
+			Allowed:         false,
+			RetryAfter:      0,
+			RemainingTokens: 0,
+			TotalTokens:     cl.capacity,
+		}, err
+	}
+
+	values, err := parseScriptValues(results, 4)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the concurrency acquire script result")
+		return nil, err
+	}
+
+	response := &models.LimiterResponse{
+		Allowed:         values[0] == 1,
+		RetryAfter:      secondsUntil(time.Duration(values[2]) * time.Millisecond),
+		RemainingTokens: values[1],
+		TotalTokens:     cl.capacity,
+		Reset:           secondsUntil(time.Duration(values[3]) * time.Millisecond),
+	}
+
+	if response.Allowed {
+		response.LeaseId = leaseId
+	} else {
+		log.Warn().Str("scope", scope).Msg("Request is rejected, too many requests in flight")
+	}
+
+	return response, nil
+}
+
+func (cl *ConcurrencyLimiterRedis) Release(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier, leaseId string) (bool, error) {
+	releaseScript := redis.NewScript(lua.GetConcurrencyReleaseScript())
+
+	results, err := cb.Cb.Execute(func() (any, error) {
+		results, err := releaseScript.Run(ctx, rdb, concurrencyKeys(scope, identifier), leaseId).Result()
+		return results, err
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Error calling the concurrency release script")
+		return false, err
+	}
+
+	removed, ok := results.(int64)
+	if !ok {
+		return false, errors.New("unexpected concurrency release script result")
+	}
+
+	return removed == 1, nil
+}
+
+// Refund releases the lease the request was admitted with, the cost of a concurrency limit is the slots it holds
+func (cl *ConcurrencyLimiterRedis) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	_, err := cl.Release(ctx, rdb, cb, log, scope, identifier, admitted.LeaseId)
+	return err
+}
@@ -0,0 +1,115 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"goapp/constants"
+	"goapp/metrics"
+	"goapp/models"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker"
+)
+
+// failSafeLimiter turns a backend failure into a plain allow (fail open) or deny (fail closed) decision
+type failSafeLimiter struct {
+	base     RateLimiter
+	failOpen bool
+	mode     string
+	algo     string
+	limit    int64
+}
+
+func newFailSafeLimiter(base RateLimiter, mode, algo string, limit int64) *failSafeLimiter {
+	return &failSafeLimiter{
+		base:     base,
+		failOpen: mode == constants.FailureModeOpen,
+		mode:     mode,
+		algo:     algo,
+		limit:    limit,
+	}
+}
+
+func (fs *failSafeLimiter) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	response, err := fs.base.Allow(ctx, rdb, cb, log, scope, identifier, cost)
+	if err == nil {
+		return response, nil
+	}
+
+	reason := FailureReason(err)
+	metrics.DegradedDecisions.WithLabelValues(fs.algo, fs.mode, reason).Inc()
+	log.Warn().Err(err).Str("scope", scope).Str("failureMode", fs.mode).Msg("Limiter backend failed, applying the policy failure mode")
+
+	if response == nil {
+		response = &models.LimiterResponse{TotalTokens: fs.limit}
+	}
+	response.Allowed = fs.failOpen
+	response.Degraded = true
+	response.Reason = reason
+
+	return response, nil
+}
+
+func (fs *failSafeLimiter) Release(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier, leaseId string) (bool, error) {
+	return ReleaseLease(ctx, fs.base, rdb, cb, log, scope, identifier, leaseId)
+}
+
+// Refund skips the decisions taken by the failure mode, the backend charged nothing for them
+func (fs *failSafeLimiter) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	if admitted.Degraded {
+		return nil
+	}
+	return RefundCost(ctx, fs.base, rdb, cb, log, scope, identifier, cost, admitted)
+}
+
+func (fs *failSafeLimiter) Close() {
+	closeLimiter(fs.base)
+}
+
+// FailureReason tells why a decision was taken without the backend, for the reason of degraded decisions
+func FailureReason(err error) string {
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return constants.ReasonCircuitOpen
+	}
+	return constants.ReasonBackendUnavailable
+}
+
+// withFailureMode wraps the limiter according to what the policy wants to happen when its backend fails
+func (f *DefaultLimiterFactory) withFailureMode(limiter RateLimiter, policy *models.PolicySchema, algo map[string]constructor, rateLimitType string, log zerolog.Logger) RateLimiter {
+	return f.applyFailureMode(limiter, policy.FailureMode, rateLimitType, policy.EffectiveAlgorithm(), int64(policy.Limit), log, func() RateLimiter {
+		local, ok := algo[constants.ValeTypeMemory]
+		if !ok {
+			return nil
+		}
+		return local(localShare(policy, f.fallback.ExpectedInstances), f.keys, log)
+	})
+}
+
+// applyFailureMode wraps the limiter for the given failure mode, local builds the in-memory limiter used as fallback.
+// Without a failure mode the limiter falls back to in-memory when it is enabled globally and errors out otherwise
+func (f *DefaultLimiterFactory) applyFailureMode(limiter RateLimiter, mode, rateLimitType, algorithm string, limit int64, log zerolog.Logger, local func() RateLimiter) RateLimiter {
+	if mode == "" && f.fallback.Enabled {
+		mode = constants.FailureModeFallback
+	}
+
+	switch mode {
+	case constants.FailureModeFallback:
+		// only the redis limiters have a backend that can go away
+		if rateLimitType != constants.ValueTypeRedis {
+			return limiter
+		}
+		localLimiter := local()
+		if localLimiter == nil {
+			return limiter
+		}
+		return newFallbackLimiter(limiter, localLimiter, algorithm)
+	case constants.FailureModeOpen, constants.FailureModeClosed:
+		return newFailSafeLimiter(limiter, mode, algorithm, limit)
+	case "":
+		return limiter
+	default:
+		log.Warn().Str("failureMode", mode).Msg("Unknown failure mode on policy, backend errors will be returned as is")
+		return limiter
+	}
+}
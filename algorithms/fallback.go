@@ -0,0 +1,105 @@
+package algorithms
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/metrics"
+	"goapp/models"
+	"math"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker"
+)
+
+// fallbackLimiter routes the decision to a local in-memory limiter whenever the redis limiter cannot answer
+type fallbackLimiter struct {
+	primary RateLimiter
+	local   RateLimiter
+	algo    string
+}
+
+func newFallbackLimiter(primary, local RateLimiter, algo string) *fallbackLimiter {
+	return &fallbackLimiter{
+		primary: primary,
+		local:   local,
+		algo:    algo,
+	}
+}
+
+func (fl *fallbackLimiter) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	// skip the round trip entirely while the breaker is open
+	if cb.Cb.State() == gobreaker.StateOpen {
+		return fl.degraded(ctx, rdb, cb, log, scope, identifier, cost, constants.ReasonCircuitOpen)
+	}
+
+	response, err := fl.primary.Allow(ctx, rdb, cb, log, scope, identifier, cost)
+	if err == nil {
+		return response, nil
+	}
+
+	log.Warn().Err(err).Str("scope", scope).Msg("Redis limiter failed, falling back to the in-memory limiter")
+	return fl.degraded(ctx, rdb, cb, log, scope, identifier, cost, FailureReason(err))
+}
+
+func (fl *fallbackLimiter) degraded(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, reason string) (*models.LimiterResponse, error) {
+	metrics.DegradedDecisions.WithLabelValues(fl.algo, constants.FailureModeFallback, reason).Inc()
+
+	response, err := fl.local.Allow(ctx, rdb, cb, log, scope, identifier, cost)
+	if response != nil {
+		response.Degraded = true
+		response.Reason = reason
+	}
+	return response, err
+}
+
+// Release hands the lease back to whichever limiter granted it, the local one may have while redis was down
+func (fl *fallbackLimiter) Release(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier, leaseId string) (bool, error) {
+	if cb.Cb.State() != gobreaker.StateOpen {
+		released, err := ReleaseLease(ctx, fl.primary, rdb, cb, log, scope, identifier, leaseId)
+		if err == nil && released {
+			return true, nil
+		}
+	}
+
+	return ReleaseLease(ctx, fl.local, rdb, cb, log, scope, identifier, leaseId)
+}
+
+// Refund hands the cost back to whichever limiter charged it
+func (fl *fallbackLimiter) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	if admitted.Degraded {
+		return RefundCost(ctx, fl.local, rdb, cb, log, scope, identifier, cost, admitted)
+	}
+	return RefundCost(ctx, fl.primary, rdb, cb, log, scope, identifier, cost, admitted)
+}
+
+func (fl *fallbackLimiter) Close() {
+	closeLimiter(fl.primary)
+	closeLimiter(fl.local)
+}
+
+// localShare scales the policy down to the part of the global limit a single instance may hand out on its own
+func localShare(policy *models.PolicySchema, expectedInstances int) *models.PolicySchema {
+	share := *policy
+	if expectedInstances <= 1 {
+		return &share
+	}
+
+	share.Limit = scaleDown(policy.Limit, expectedInstances)
+	if policy.Burst > 0 {
+		share.Burst = scaleDown(policy.Burst, expectedInstances)
+	}
+
+	if len(policy.Rules) > 0 {
+		share.Rules = make([]models.LimitRule, len(policy.Rules))
+		for i, rule := range policy.Rules {
+			share.Rules[i] = rule
+			share.Rules[i].Limit = scaleDown(rule.Limit, expectedInstances)
+		}
+	}
+	return &share
+}
+
+func scaleDown(limit, expectedInstances int) int {
+	return int(math.Max(1, math.Ceil(float64(limit)/float64(expectedInstances))))
+}
@@ -5,8 +5,6 @@ import (
 	"goapp/constants"
 	"goapp/lua"
 	"goapp/models"
-	"goapp/services"
-	"goapp/utils"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -35,41 +33,54 @@ func NewFixedWindowCounter(windowStr string, capacity int64, log zerolog.Logger)
 	}
 }
 
-func (fc *FixedCounterRedis) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, scope, identifier string) (*models.LimiterResponse, error) {
-	now := time.Now().UnixNano()
+func (fc *FixedCounterRedis) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	now := time.Now().UnixMilli()
 
-	window := fc.window.Microseconds()
+	window := fc.window.Milliseconds()
 
-	redisKey := utils.StringBuilder(constants.KeyRateLimit, constants.AlgorithmFixedWindow, scope, identifier)
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmFixedWindow, scope, identifier)
 
 	fwcScript := redis.NewScript(lua.GetFixedWindowCounterScript())
 
 	results, err := cb.Cb.Execute(func() (any, error) {
-		results, err := fwcScript.Run(ctx, rdb, []string{redisKey}, fc.capacity, window, now, 1).Result()
+		results, err := fwcScript.Run(ctx, rdb, []string{redisKey}, fc.capacity, window, now, cost).Result()
 		return results, err
 	})
 
-	allowed := results.([]any)[0].(bool)
-	tokens := results.([]any)[1].(int64)
-
-	retryAfter := now + (fc.window.Microseconds() - tokens)
-
 	if err != nil {
 		log.Error().Err(err).Msg("Error calling the fixed window counter script, rejecting the request")
 		return &models.LimiterResponse{
-			Allowed:       false,
-			RetryAfter:    int64(retryAfter),
-			RemainingTokens: int64(tokens),
-			TotalTokens : fc.capacity,
+			Allowed:         false,
+			RetryAfter:      0,
+			RemainingTokens: 0,
+			TotalTokens:     fc.capacity,
 		}, err
-	} else {
-		log.Info().Msg("Accepting the request")
 	}
 
-	return &models.LimiterResponse{
-		Allowed:       allowed,
-		RetryAfter:    int64(retryAfter),
-		RemainingTokens: int64(tokens),
-		TotalTokens : fc.capacity,
-	}, nil
+	values, err := parseScriptValues(results, 3)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the fixed window counter script result")
+		return nil, err
+	}
+
+	reset := secondsUntil(time.Duration(values[2]) * time.Millisecond)
+	response := &models.LimiterResponse{
+		Allowed:         values[0] == 1,
+		RemainingTokens: values[1],
+		TotalTokens:     fc.capacity,
+		Reset:           reset,
+		Window:          secondsUntil(fc.window),
+	}
+
+	if !response.Allowed {
+		log.Warn().Str("scope", scope).Msg("Request is rejected, window is exhausted")
+		response.RetryAfter = reset
+	}
+
+	return response, nil
+}
+
+func (fc *FixedCounterRedis) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmFixedWindow, scope, identifier)
+	return runRefundScript(ctx, rdb, cb, lua.GetFixedWindowRefundScript(), []string{redisKey}, fc.capacity, fc.window.Milliseconds(), time.Now().UnixMilli(), cost)
 }
@@ -0,0 +1,97 @@
+package algorithms
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/lua"
+	"goapp/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+type GCRARedis struct {
+	emissionInterval time.Duration
+	tolerance        time.Duration
+	capacity         int64
+}
+
+// NewGCRA spaces the requests limit per window apart, letting up to burst of them (limit when unset) through at once
+func NewGCRA(windowStr string, limit, burst int64, log zerolog.Logger) *GCRARedis {
+	emissionInterval, tolerance, capacity := gcraParams(windowStr, limit, burst, log)
+
+	return &GCRARedis{
+		emissionInterval: emissionInterval,
+		tolerance:        tolerance,
+		capacity:         capacity,
+	}
+}
+
+func gcraParams(windowStr string, limit, burst int64, log zerolog.Logger) (time.Duration, time.Duration, int64) {
+	window, err := time.ParseDuration(windowStr)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the duration")
+	}
+
+	if limit <= 0 {
+		log.Error().Int64("limit", limit).Msg("GCRA needs a positive limit")
+		limit = 1
+	}
+
+	capacity := burst
+	if capacity <= 0 {
+		capacity = limit
+	}
+
+	emissionInterval := window / time.Duration(limit)
+	return emissionInterval, emissionInterval * time.Duration(capacity), capacity
+}
+
+func (g *GCRARedis) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	now := time.Now().UnixMicro()
+
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmGCRA, scope, identifier)
+
+	gcraScript := redis.NewScript(lua.GetGCRAScript())
+
+	results, err := cb.Cb.Execute(func() (any, error) {
+		results, err := gcraScript.Run(ctx, rdb, []string{redisKey}, g.emissionInterval.Microseconds(), g.tolerance.Microseconds(), now, cost).Result()
+		return results, err
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Error calling the gcra script, rejecting the request")
+		return &models.LimiterResponse{
+			Allowed:         false,
+			RetryAfter:      0,
+			RemainingTokens: 0,
+			TotalTokens:     g.capacity,
+		}, err
+	}
+
+	values, err := parseScriptValues(results, 4)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the gcra script result")
+		return nil, err
+	}
+
+	allowed := values[0] == 1
+	if !allowed {
+		log.Warn().Str("scope", scope).Msg("Request is rejected, arriving ahead of its theoretical arrival time")
+	}
+
+	return &models.LimiterResponse{
+		Allowed:         allowed,
+		RetryAfter:      secondsUntil(time.Duration(values[2]) * time.Microsecond),
+		RemainingTokens: values[1],
+		TotalTokens:     g.capacity,
+		Reset:           secondsUntil(time.Duration(values[3]) * time.Microsecond),
+		Window:          secondsUntil(g.tolerance),
+	}, nil
+}
+
+func (g *GCRARedis) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmGCRA, scope, identifier)
+	return runRefundScript(ctx, rdb, cb, lua.GetGCRARefundScript(), []string{redisKey}, g.emissionInterval.Microseconds(), time.Now().UnixMicro(), cost)
+}
@@ -0,0 +1,52 @@
+package algorithms
+
+import (
+	"errors"
+	"fmt"
+	"goapp/constants"
+	"strings"
+)
+
+// levelSeparator joins the identifiers of a level's parents to its own, so that user u_17 of tenant acme is limited
+// on user:acme/u_17 and never shares a counter with a user of the same name in another tenant
+const levelSeparator = "/"
+
+var ErrInvalidLevel = errors.New("invalid hierarchy level")
+
+// Level is one step of the global → tenant → user → route hierarchy, backed by the policy of scope:identifier
+type Level struct {
+	Name       string
+	Scope      string
+	Identifier string
+}
+
+// hierarchyLevels lists the levels below global, from the broadest to the most specific
+var hierarchyLevels = []string{constants.LevelTenant, constants.LevelUser, constants.LevelRoute}
+
+// BuildLevels returns the levels applicable to a request, the global level always applies once any other level is given.
+// The identifier of a level is prefixed with those of all its parents, left empty when a parent is not named, so
+// a user is keyed <tenant>/<user> and a route <tenant>/<user>/<route>. Only the route, the last segment, may contain
+// the separator, anywhere else it would make two keys collide
+func BuildLevels(values map[string]string) ([]Level, error) {
+	levels := make([]Level, 0, len(hierarchyLevels)+1)
+	parents := make([]string, 0, len(hierarchyLevels))
+	for i, name := range hierarchyLevels {
+		value := values[name]
+		if i < len(hierarchyLevels)-1 && strings.Contains(value, levelSeparator) {
+			return nil, fmt.Errorf("%w: %s cannot contain %q", ErrInvalidLevel, name, levelSeparator)
+		}
+
+		if value != "" {
+			identifier := strings.Join(append(parents, value), levelSeparator)
+			levels = append(levels, Level{Name: name, Scope: name, Identifier: identifier})
+		}
+		parents = append(parents, value)
+	}
+
+	if len(levels) == 0 {
+		return nil, nil
+	}
+
+	global := Level{Name: constants.LevelGlobal, Scope: constants.LevelGlobal, Identifier: constants.GlobalIdentifier}
+	return append([]Level{global}, levels...), nil
+}
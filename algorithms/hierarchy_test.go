@@ -0,0 +1,65 @@
+package algorithms_test
+
+import (
+	"errors"
+	"goapp/algorithms"
+	"goapp/constants"
+	"reflect"
+	"testing"
+)
+
+func TestBuildLevels(t *testing.T) {
+	global := algorithms.Level{Name: constants.LevelGlobal, Scope: constants.LevelGlobal, Identifier: constants.GlobalIdentifier}
+	level := func(name, identifier string) algorithms.Level {
+		return algorithms.Level{Name: name, Scope: name, Identifier: identifier}
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		levels []algorithms.Level
+		err    error
+	}{
+		{
+			name:   "every level",
+			values: map[string]string{constants.LevelTenant: "acme", constants.LevelUser: "u_17", constants.LevelRoute: "/search"},
+			levels: []algorithms.Level{global, level(constants.LevelTenant, "acme"), level(constants.LevelUser, "acme/u_17"), level(constants.LevelRoute, "acme/u_17//search")},
+		},
+		{
+			name:   "user without a tenant",
+			values: map[string]string{constants.LevelUser: "u_17"},
+			levels: []algorithms.Level{global, level(constants.LevelUser, "/u_17")},
+		},
+		{
+			name:   "route of a tenant",
+			values: map[string]string{constants.LevelTenant: "acme", constants.LevelRoute: "/search"},
+			levels: []algorithms.Level{global, level(constants.LevelTenant, "acme"), level(constants.LevelRoute, "acme///search")},
+		},
+		{
+			name:   "no level",
+			values: map[string]string{},
+		},
+		{
+			name:   "separator in a tenant",
+			values: map[string]string{constants.LevelTenant: "acme/eu", constants.LevelUser: "u_17"},
+			err:    algorithms.ErrInvalidLevel,
+		},
+		{
+			name:   "separator in a user",
+			values: map[string]string{constants.LevelUser: "u/17"},
+			err:    algorithms.ErrInvalidLevel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels, err := algorithms.BuildLevels(tt.values)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(levels, tt.levels) {
+				t.Fatalf("expected levels %+v, got %+v", tt.levels, levels)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package algorithms
+
+import (
+	"goapp/models"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type limiterInstance struct {
+	limiter  RateLimiter
+	policy   models.PolicySchema
+	lastUsed atomic.Int64
+}
+
+func newLimiterInstance(policy *models.PolicySchema, limiter RateLimiter) *limiterInstance {
+	instance := &limiterInstance{
+		limiter: limiter,
+		policy:  *policy,
+	}
+	instance.lastUsed.Store(time.Now().UnixNano())
+	return instance
+}
+
+// instanceRegistry keeps the constructed limiters alive between requests so that
+// the per key state held by the in-memory algorithms is not thrown away
+type instanceRegistry struct {
+	instances sync.Map
+	idleTTL   time.Duration
+}
+
+func newInstanceRegistry(idleTTL time.Duration) *instanceRegistry {
+	return &instanceRegistry{
+		instances: sync.Map{},
+		idleTTL:   idleTTL,
+	}
+}
+
+func (r *instanceRegistry) get(key string, policy *models.PolicySchema, build func() RateLimiter) RateLimiter {
+	now := time.Now().UnixNano()
+
+	val, ok := r.instances.Load(key)
+	if !ok {
+		fresh := newLimiterInstance(policy, build())
+
+		var loaded bool
+		val, loaded = r.instances.LoadOrStore(key, fresh)
+		if loaded {
+			closeLimiter(fresh.limiter)
+		}
+	}
+
+	instance := val.(*limiterInstance)
+	if reflect.DeepEqual(instance.policy, *policy) {
+		instance.lastUsed.Store(now)
+		return instance.limiter
+	}
+
+	// the policy has changed since the limiter was built, replace the stale instance
+	fresh := newLimiterInstance(policy, build())
+	if r.instances.CompareAndSwap(key, val, fresh) {
+		closeLimiter(instance.limiter)
+		return fresh.limiter
+	}
+
+	// another request already swapped the instance, use whatever is stored now
+	closeLimiter(fresh.limiter)
+	if val, ok := r.instances.Load(key); ok {
+		return val.(*limiterInstance).limiter
+	}
+	return instance.limiter
+}
+
+// evictPolicy drops every instance built for the policy stored under the key
+func (r *instanceRegistry) evictPolicy(policyKey string, log zerolog.Logger) {
+	evicted := 0
+
+	r.instances.Range(func(key, val any) bool {
+		instance := val.(*limiterInstance)
+		if instance.policy.Key() == policyKey && r.instances.CompareAndDelete(key, val) {
+			closeLimiter(instance.limiter)
+			evicted++
+		}
+		return true
+	})
+
+	if evicted > 0 {
+		log.Debug().Int("evicted", evicted).Str("policyKey", policyKey).Msg("Evicted the limiter instances of a changed policy")
+	}
+}
+
+func (r *instanceRegistry) evictIdle(log zerolog.Logger) {
+	threshold := time.Now().Add(-r.idleTTL).UnixNano()
+	evicted := 0
+
+	r.instances.Range(func(key, val any) bool {
+		instance := val.(*limiterInstance)
+		if instance.lastUsed.Load() < threshold && r.instances.CompareAndDelete(key, val) {
+			closeLimiter(instance.limiter)
+			evicted++
+		}
+		return true
+	})
+
+	if evicted > 0 {
+		log.Debug().Int("evicted", evicted).Msg("Evicted idle limiter instances")
+	}
+}
@@ -6,27 +6,26 @@ import (
 	"goapp/constants"
 	"goapp/metrics"
 	"goapp/models"
-	"goapp/services"
-	"goapp/store"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 )
 
 type RateLimiter interface {
-	Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, tenantId string, userId string) (*models.LimiterResponse, error)
+	Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, tenantId string, userId string, cost int64) (*models.LimiterResponse, error)
 }
 
 type metricsLimiter struct {
-	base RateLimiter
-	algo string
+	base     RateLimiter
+	algo     string
+	version  int64
+	schedule string
 }
 
-func (m *metricsLimiter) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, tenantId string, userId string) (*models.LimiterResponse, error) {
+func (m *metricsLimiter) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, tenantId string, userId string, cost int64) (*models.LimiterResponse, error) {
 	start := time.Now()
-	allowed, err := m.base.Allow(ctx, rdb, cb, log, tenantId, userId)
+	allowed, err := m.base.Allow(ctx, rdb, cb, log, tenantId, userId, cost)
 	duration := time.Since(start).Seconds()
 
 	metrics.RequestsLatency.Observe(duration)
@@ -40,63 +39,181 @@ func (m *metricsLimiter) Allow(ctx context.Context, rdb *redis.Client, cb *servi
 
 	metrics.Requests.WithLabelValues(status, m.algo).Inc()
 
+	// hierarchies report the version of the level that decided on their own
+	if allowed != nil && m.version != 0 {
+		allowed.PolicyVersion = m.version
+	}
+	if allowed != nil && m.schedule != "" {
+		allowed.Schedule = m.schedule
+	}
+
 	return allowed, err
 }
 
+func (m *metricsLimiter) Release(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier, leaseId string) (bool, error) {
+	return ReleaseLease(ctx, m.base, rdb, cb, log, scope, identifier, leaseId)
+}
+
+func (m *metricsLimiter) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	return RefundCost(ctx, m.base, rdb, cb, log, scope, identifier, cost, admitted)
+}
+
+func (m *metricsLimiter) Close() {
+	closeLimiter(m.base)
+}
+
+// closeLimiter releases the state held by limiters that keep it in process
+func closeLimiter(limiter RateLimiter) {
+	if closer, ok := limiter.(interface{ Close() }); ok {
+		closer.Close()
+	}
+}
+
 type LimiterFactory interface {
-	GetLimiter(ctx context.Context, db *store.Db, log zerolog.Logger, scope, identifier, rateLimitType, query string, cache *services.Cache) (RateLimiter, error)
+	Limiter(policy *models.PolicySchema, rateLimitType string, log zerolog.Logger) (RateLimiter, error)
+}
+
+type DefaultLimiterFactory struct {
+	instances *instanceRegistry
+	keys      *keyTracker
+	sweep     time.Duration
+	fallback  models.Fallback
+}
+
+func NewDefaultLimiterFactory(memoryStore models.MemoryStore, fallback models.Fallback, log zerolog.Logger) *DefaultLimiterFactory {
+	sweep := constants.LimiterJanitorInterval
+	if memoryStore.SweepInterval != "" {
+		interval, err := time.ParseDuration(memoryStore.SweepInterval)
+		if err != nil || interval <= 0 {
+			log.Warn().Str("sweepInterval", memoryStore.SweepInterval).Msg("Invalid memory store sweep interval, using the default")
+		} else {
+			sweep = interval
+		}
+	}
+
+	sampleSize := memoryStore.EvictionSamples
+	if sampleSize <= 0 {
+		sampleSize = constants.EvictionSampleSize
+	}
+
+	return &DefaultLimiterFactory{
+		instances: newInstanceRegistry(constants.LimiterIdleTimeout),
+		keys:      newKeyTracker(memoryStore.MaxKeys, sampleSize),
+		sweep:     sweep,
+		fallback:  fallback,
+	}
+}
+
+// StartJanitor periodically drops the idle limiter instances and the expired in-memory keys
+func (f *DefaultLimiterFactory) StartJanitor(ctx context.Context, log zerolog.Logger) {
+	ticker := time.NewTicker(f.sweep)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				f.instances.evictIdle(log)
+				f.keys.sweep(log)
+			}
+		}
+	}()
+}
+
+// PolicyWatcher reports the keys of the policies that changed, the policy repositories are one
+type PolicyWatcher interface {
+	Watch(ctx context.Context, log zerolog.Logger, onChange func(policyKey string))
 }
 
-type DefaultLimiterFactory struct{}
+// Follow drops the limiters of a policy as soon as the repository reports it changed instead of on their next use,
+// so a deleted policy gives back the keys it held in memory right away. A change of any policy is left to the next use,
+// dropping every limiter would throw away the in-memory state of the policies that did not change
+func (f *DefaultLimiterFactory) Follow(ctx context.Context, log zerolog.Logger, policies PolicyWatcher) {
+	policies.Watch(ctx, log, func(policyKey string) {
+		if policyKey != "" {
+			f.instances.evictPolicy(policyKey, log)
+		}
+	})
+}
 
-type constructor func(policy *services.PolicySchema, log zerolog.Logger) RateLimiter
+type constructor func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter
 
 var registry = map[string]map[string]constructor{
 	constants.AlgorithmTokenBucket: {
-		constants.ValeTypeMemory: func(policy *services.PolicySchema, log zerolog.Logger) RateLimiter {
-			return NewTokenBucketMem(float64(policy.Limit), float64(policy.Burst), log)
+		constants.ValeTypeMemory: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewTokenBucketMem(float64(policy.Limit), float64(policy.Burst), tracker, log)
 		},
-		constants.ValueTypeRedis: func(policy *services.PolicySchema, log zerolog.Logger) RateLimiter {
+		constants.ValueTypeRedis: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
 			return NewTokenBucket(float64(policy.Limit), float64(policy.Burst), log)
 		},
 	},
 	constants.AlgorithmLeakyBucket: {
-		constants.ValeTypeMemory: func(policy *services.PolicySchema, log zerolog.Logger) RateLimiter {
-			return NewLeakyBucketMem(float64(policy.Limit), float64(policy.Burst), log)
+		constants.ValeTypeMemory: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewLeakyBucketMem(float64(policy.Limit), float64(policy.Burst), tracker, log)
 		},
-		constants.ValueTypeRedis: func(policy *services.PolicySchema, log zerolog.Logger) RateLimiter {
+		constants.ValueTypeRedis: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
 			return NewLeakyBucket(float64(policy.Limit), float64(policy.Burst), log)
 		},
 	},
 	constants.AlgorithmFixedWindow: {
-		constants.ValeTypeMemory: func(policy *services.PolicySchema, log zerolog.Logger) RateLimiter {
-			return NewFixedWindowMem(policy.Window, policy.Limit, log)
+		constants.ValeTypeMemory: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewFixedWindowMem(policy.Window, policy.Limit, tracker, log)
 		},
-		constants.ValueTypeRedis: func(policy *services.PolicySchema, log zerolog.Logger) RateLimiter {
+		constants.ValueTypeRedis: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
 			return NewFixedWindowCounter(policy.Window, int64(policy.Limit), log)
 		},
 	},
 	constants.AlgorithmSlidingWindow: {
-		constants.ValeTypeMemory: func(policy *services.PolicySchema, log zerolog.Logger) RateLimiter {
-			return NewSlidingWindowMem(policy.Window, policy.Limit, log)
+		constants.ValeTypeMemory: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewSlidingWindowMem(policy.Window, policy.Limit, tracker, log)
 		},
-		constants.ValueTypeRedis: func(policy *services.PolicySchema, log zerolog.Logger) RateLimiter {
+		constants.ValueTypeRedis: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
 			return NewSlidingWindowCounter(policy.Window, policy.Limit, log)
 		},
 	},
+	constants.AlgorithmSlidingLog: {
+		constants.ValeTypeMemory: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewSlidingWindowLogMem(policy.Window, policy.Limit, tracker, log)
+		},
+		constants.ValueTypeRedis: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewSlidingWindowLog(policy.Window, int64(policy.Limit), log)
+		},
+	},
+	constants.AlgorithmGCRA: {
+		constants.ValeTypeMemory: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewGCRAMem(policy.Window, int64(policy.Limit), int64(policy.Burst), tracker, log)
+		},
+		constants.ValueTypeRedis: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewGCRA(policy.Window, int64(policy.Limit), int64(policy.Burst), log)
+		},
+	},
+	constants.AlgorithmConcurrency: {
+		constants.ValeTypeMemory: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewConcurrencyLimiterMem(policy.Window, int64(policy.Limit), tracker, log)
+		},
+		constants.ValueTypeRedis: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewConcurrencyLimiter(policy.Window, int64(policy.Limit), log)
+		},
+	},
+	constants.AlgorithmMultiWindow: {
+		constants.ValeTypeMemory: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewMultiWindowMem(policy.Rules, tracker, log)
+		},
+		constants.ValueTypeRedis: func(policy *models.PolicySchema, tracker *keyTracker, log zerolog.Logger) RateLimiter {
+			return NewMultiWindow(policy.Rules, log)
+		},
+	},
 }
 
-func (f *DefaultLimiterFactory) GetLimiter(ctx context.Context, db *pgxpool.Pool, log zerolog.Logger, scope, identifier, rateLimitType, query string, cache *services.Cache) (RateLimiter, error) {
-	policy, exists := cache.GetPolicy(ctx, db, log, scope, identifier, query)
-	if !exists {
-		// call the database to get the policy and update the cache
-		return nil, fmt.Errorf("no policy found for scope : %s and identifier : %s", scope, identifier)
-	}
-
+// Limiter returns the instance limiting the keys of the policy, building it on first use
+func (f *DefaultLimiterFactory) Limiter(policy *models.PolicySchema, rateLimitType string, log zerolog.Logger) (RateLimiter, error) {
 	// // Implement logic to create and return the appropriate limiter based on the type and algorithm
-	algo, ok := registry[policy.Algorithm]
+	algorithm := policy.EffectiveAlgorithm()
+	algo, ok := registry[algorithm]
 	if !ok {
-		return nil, fmt.Errorf("unsupported algorithm: %s", policy.Algorithm)
+		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
 	}
 
 	constructor, ok := algo[rateLimitType]
@@ -104,9 +221,19 @@ func (f *DefaultLimiterFactory) GetLimiter(ctx context.Context, db *pgxpool.Pool
 		return nil, fmt.Errorf("unsupported limiter type: %s", rateLimitType)
 	}
 
-	limiter := constructor(policy, log)
-	return &metricsLimiter{
-		base: limiter,
-		algo: policy.Algorithm,
-	}, nil
+	// one instance per policy, a pattern is shared by every key it matches and the limiters keep the state of each
+	// key apart themselves, so the memory held for a pattern stays bounded by the key tracker
+	instanceKey := StringBuilder(rateLimitType, algorithm, policy.Scope, policy.Identifier)
+
+	limiter := f.instances.get(instanceKey, policy, func() RateLimiter {
+		base := constructor(policy, f.keys, log)
+
+		return &metricsLimiter{
+			base:     withMode(f.withFailureMode(base, policy, algo, rateLimitType, log), policy),
+			algo:     algorithm,
+			version:  policy.Version,
+			schedule: policy.ActiveSchedule,
+		}
+	})
+	return limiter, nil
 }
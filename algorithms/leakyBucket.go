@@ -5,8 +5,6 @@ import (
 	"goapp/constants"
 	"goapp/lua"
 	"goapp/models"
-	"goapp/services"
-	"goapp/utils"
 	"sync"
 	"time"
 
@@ -32,40 +30,51 @@ func NewLeakyBucket(maxTokens, leakRate float64, log zerolog.Logger) *LeakyBucke
 	}
 }
 
-func (lb *LeakyBucketRedis) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, scope, identifier string) (*models.LimiterResponse, error) {
+func (lb *LeakyBucketRedis) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
 	// read data from redis
-	redisKey := utils.StringBuilder(constants.KeyRateLimit, constants.AlgorithmLeakyBucket, scope, identifier)
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmLeakyBucket, scope, identifier)
 
 	leakyScript := redis.NewScript(lua.GetLeakyBucketScript())
 
 	now := float64(time.Now().UnixNano()) / 1e9
 
 	results, err := cb.Cb.Execute(func() (any, error) {
-		results, err := leakyScript.Run(ctx, rdb, []string{redisKey}, lb.MaxTokens, lb.LeakRate, now, 1).Result()
+		results, err := leakyScript.Run(ctx, rdb, []string{redisKey}, lb.MaxTokens, lb.LeakRate, now, cost).Result()
 		return results, err
 	})
 
-	allowed := results.([]any)[0].(bool)
-	currentTokens := results.([]any)[1].(float64)
-
-	retryAfter := now + (lb.MaxTokens / lb.LeakRate)
-
 	if err != nil {
 		log.Error().Err(err).Msg("Error running the script, rejecting the request")
 		return &models.LimiterResponse{
-			Allowed:       false,
-			RetryAfter:    0,
+			Allowed:         false,
+			RetryAfter:      0,
 			RemainingTokens: 0,
-			TotalTokens : int64(lb.MaxTokens),
+			TotalTokens:     int64(lb.MaxTokens),
 		}, err
-	} else {
-		log.Info().Msg("Accepting the request")
+	}
+
+	values, err := parseScriptValues(results, 4)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the leaky bucket script result")
+		return nil, err
+	}
+
+	allowed := values[0] == 1
+	if !allowed {
+		log.Warn().Str("scope", scope).Msg("Request is getting rejected, bucket is full")
 	}
 
 	return &models.LimiterResponse{
-		Allowed:       allowed,
-		RetryAfter:    int64(retryAfter),
-		RemainingTokens: int64(currentTokens),
-		TotalTokens : int64(lb.MaxTokens),
+		Allowed:         allowed,
+		RetryAfter:      secondsUntil(time.Duration(values[2]) * time.Millisecond),
+		RemainingTokens: values[1],
+		TotalTokens:     int64(lb.MaxTokens),
+		Reset:           secondsUntil(time.Duration(values[3]) * time.Millisecond),
+		Window:          bucketWindow(lb.MaxTokens, lb.LeakRate),
 	}, nil
 }
+
+func (lb *LeakyBucketRedis) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmLeakyBucket, scope, identifier)
+	return runRefundScript(ctx, rdb, cb, lua.GetLeakyBucketRefundScript(), []string{redisKey}, cost)
+}
@@ -0,0 +1,176 @@
+package algorithms
+
+import (
+	"goapp/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+type storeEntry struct {
+	state    any
+	lastSeen atomic.Int64
+}
+
+// keyTracker enforces the global cap on the number of keys held by all the in-memory limiters
+type keyTracker struct {
+	maxKeys    int64
+	sampleSize int
+	tracked    atomic.Int64
+	stores     sync.Map
+}
+
+func newKeyTracker(maxKeys int64, sampleSize int) *keyTracker {
+	return &keyTracker{
+		maxKeys:    maxKeys,
+		sampleSize: sampleSize,
+		stores:     sync.Map{},
+	}
+}
+
+// keyStore holds the per key state of a single in-memory limiter
+type keyStore struct {
+	algo    string
+	entries sync.Map
+	size    atomic.Int64
+	tracker *keyTracker
+	gauge   prometheus.Gauge
+	expired func(state any, now time.Time) bool
+	pinned  func(state any, now time.Time) bool
+}
+
+func newKeyStore(algo string, tracker *keyTracker, expired func(state any, now time.Time) bool) *keyStore {
+	return newPinnedKeyStore(algo, tracker, expired, nil)
+}
+
+// newPinnedKeyStore is a key store whose keys are not evicted for the key cap while pinned reports them in use, they
+// still count towards the cap and are dropped by the sweep once expired
+func newPinnedKeyStore(algo string, tracker *keyTracker, expired, pinned func(state any, now time.Time) bool) *keyStore {
+	store := &keyStore{
+		algo:    algo,
+		entries: sync.Map{},
+		tracker: tracker,
+		gauge:   metrics.TrackedKeys.WithLabelValues(algo),
+		expired: expired,
+		pinned:  pinned,
+	}
+	tracker.stores.Store(store, struct{}{})
+	return store
+}
+
+// load returns the state for the key, creating it with init if the key is not tracked yet
+func (s *keyStore) load(key string, now time.Time, init func() any) any {
+	val, ok := s.entries.Load(key)
+	if !ok {
+		entry := &storeEntry{state: init()}
+		entry.lastSeen.Store(now.UnixNano())
+
+		var loaded bool
+		val, loaded = s.entries.LoadOrStore(key, entry)
+		if !loaded {
+			s.added(entry)
+		}
+	}
+
+	entry := val.(*storeEntry)
+	entry.lastSeen.Store(now.UnixNano())
+	return entry.state
+}
+
+// peek returns the state for the key without tracking it when it is unknown
+func (s *keyStore) peek(key string) (any, bool) {
+	val, ok := s.entries.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return val.(*storeEntry).state, true
+}
+
+func (s *keyStore) added(entry *storeEntry) {
+	s.size.Add(1)
+	s.gauge.Inc()
+
+	if s.tracker.tracked.Add(1) > s.tracker.maxKeys && s.tracker.maxKeys > 0 {
+		s.tracker.evictOldest(entry)
+	}
+}
+
+func (s *keyStore) remove(key, val any, reason string) bool {
+	if !s.entries.CompareAndDelete(key, val) {
+		return false
+	}
+
+	s.size.Add(-1)
+	s.gauge.Dec()
+	s.tracker.tracked.Add(-1)
+	metrics.EvictedKeys.WithLabelValues(s.algo, reason).Inc()
+	return true
+}
+
+// sweep drops every key whose state no longer affects the decision (bucket refilled, window aged out)
+func (s *keyStore) sweep(now time.Time) int {
+	removed := 0
+	s.entries.Range(func(key, val any) bool {
+		if s.expired(val.(*storeEntry).state, now) && s.remove(key, val, "expired") {
+			removed++
+		}
+		return true
+	})
+	return removed
+}
+
+// release forgets every key of the store, used when the owning limiter instance is dropped
+func (s *keyStore) release() {
+	s.tracker.stores.Delete(s)
+
+	size := s.size.Swap(0)
+	s.gauge.Sub(float64(size))
+	s.tracker.tracked.Add(-size)
+	s.entries.Clear()
+}
+
+// evictOldest samples a handful of keys across the stores and drops the least recently used one that is not pinned.
+// The key just added is never dropped, its state would be written after it was evicted and lost
+func (t *keyTracker) evictOldest(added *storeEntry) {
+	var oldestStore *keyStore
+	var oldestKey, oldestVal any
+	oldestSeen := int64(0)
+	sampled := 0
+	now := time.Now()
+
+	t.stores.Range(func(st, _ any) bool {
+		store := st.(*keyStore)
+		store.entries.Range(func(key, val any) bool {
+			entry := val.(*storeEntry)
+			seen := entry.lastSeen.Load()
+			evictable := entry != added && (store.pinned == nil || !store.pinned(entry.state, now))
+			if evictable && (oldestStore == nil || seen < oldestSeen) {
+				oldestStore, oldestKey, oldestVal, oldestSeen = store, key, val, seen
+			}
+			sampled++
+			return sampled < t.sampleSize
+		})
+		return sampled < t.sampleSize
+	})
+
+	if oldestStore != nil {
+		oldestStore.remove(oldestKey, oldestVal, "capacity")
+	}
+}
+
+func (t *keyTracker) sweep(log zerolog.Logger) {
+	now := time.Now()
+	removed := 0
+
+	t.stores.Range(func(st, _ any) bool {
+		removed += st.(*keyStore).sweep(now)
+		return true
+	})
+
+	if removed > 0 {
+		log.Debug().Int("removed", removed).Msg("Removed expired in-memory limiter keys")
+	}
+}
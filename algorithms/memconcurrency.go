@@ -0,0 +1,170 @@
+package algorithms
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+type concurrencyLease struct {
+	expiry time.Time
+	slots  int64
+}
+
+type ConcurrencyStore struct {
+	leases   map[string]concurrencyLease
+	inFlight int64
+	mu       sync.Mutex
+}
+
+// expire drops the leases of clients that never released them
+func (s *ConcurrencyStore) expire(now time.Time) {
+	for leaseId, lease := range s.leases {
+		if !lease.expiry.After(now) {
+			delete(s.leases, leaseId)
+			s.inFlight -= lease.slots
+		}
+	}
+}
+
+func (s *ConcurrencyStore) earliestExpiry() time.Time {
+	var earliest time.Time
+	for _, lease := range s.leases {
+		if earliest.IsZero() || lease.expiry.Before(earliest) {
+			earliest = lease.expiry
+		}
+	}
+	return earliest
+}
+
+// latestExpiry is when every slot is free again at the latest, unless the leases are released before
+func (s *ConcurrencyStore) latestExpiry() time.Time {
+	var latest time.Time
+	for _, lease := range s.leases {
+		if lease.expiry.After(latest) {
+			latest = lease.expiry
+		}
+	}
+	return latest
+}
+
+type ConcurrencyLimiter struct {
+	capacity int64
+	leaseTTL time.Duration
+	leases   *keyStore
+}
+
+func NewConcurrencyLimiterMem(windowStr string, capacity int64, tracker *keyTracker, log zerolog.Logger) *ConcurrencyLimiter {
+	cl := &ConcurrencyLimiter{
+		capacity: capacity,
+		leaseTTL: leaseTTL(windowStr, log),
+	}
+	// evicting a key with leases outstanding would forget them and admit more requests than the capacity
+	cl.leases = newPinnedKeyStore(constants.AlgorithmConcurrency, tracker, cl.idle, cl.held)
+	return cl
+}
+
+// idle reports whether no lease is held anymore
+func (cl *ConcurrencyLimiter) idle(state any, now time.Time) bool {
+	leaseStore := state.(*ConcurrencyStore)
+	leaseStore.mu.Lock()
+	defer leaseStore.mu.Unlock()
+
+	leaseStore.expire(now)
+	return len(leaseStore.leases) == 0
+}
+
+// held reports whether a lease is still outstanding
+func (cl *ConcurrencyLimiter) held(state any, now time.Time) bool {
+	return !cl.idle(state, now)
+}
+
+func (cl *ConcurrencyLimiter) Close() {
+	cl.leases.release()
+}
+
+func (cl *ConcurrencyLimiter) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmConcurrency, scope, identifier)
+	now := time.Now()
+
+	val := cl.leases.load(key, now, func() any {
+		return &ConcurrencyStore{
+			leases: make(map[string]concurrencyLease),
+		}
+	})
+
+	leaseStore := val.(*ConcurrencyStore)
+	leaseStore.mu.Lock()
+	defer leaseStore.mu.Unlock()
+
+	leaseStore.expire(now)
+
+	if leaseStore.inFlight+cost > cl.capacity {
+		log.Warn().Str("scope", scope).Msg("Request is rejected, too many requests in flight")
+
+		var retryAfter time.Duration
+		if earliest := leaseStore.earliestExpiry(); !earliest.IsZero() {
+			retryAfter = earliest.Sub(now)
+		}
+
+		return &models.LimiterResponse{
+			Allowed:         false,
+			RetryAfter:      secondsUntil(retryAfter),
+			RemainingTokens: cl.capacity - leaseStore.inFlight,
+			TotalTokens:     cl.capacity,
+			Reset:           secondsUntil(leaseStore.latestExpiry().Sub(now)),
+		}, nil
+	}
+
+	leaseId := uuid.NewString()
+	leaseStore.leases[leaseId] = concurrencyLease{
+		expiry: now.Add(cl.leaseTTL),
+		slots:  cost,
+	}
+	leaseStore.inFlight += cost
+	log.Debug().Str("scope", scope).Msg("Request is allowed")
+
+	return &models.LimiterResponse{
+		Allowed:         true,
+		RetryAfter:      0,
+		RemainingTokens: cl.capacity - leaseStore.inFlight,
+		TotalTokens:     cl.capacity,
+		Reset:           secondsUntil(leaseStore.latestExpiry().Sub(now)),
+		LeaseId:         leaseId,
+	}, nil
+}
+
+func (cl *ConcurrencyLimiter) Release(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier, leaseId string) (bool, error) {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmConcurrency, scope, identifier)
+
+	val, ok := cl.leases.peek(key)
+	if !ok {
+		return false, nil
+	}
+
+	leaseStore := val.(*ConcurrencyStore)
+	leaseStore.mu.Lock()
+	defer leaseStore.mu.Unlock()
+
+	leaseStore.expire(time.Now())
+
+	lease, ok := leaseStore.leases[leaseId]
+	if !ok {
+		return false, nil
+	}
+
+	delete(leaseStore.leases, leaseId)
+	leaseStore.inFlight -= lease.slots
+	return true, nil
+}
+
+func (cl *ConcurrencyLimiter) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	_, err := cl.Release(ctx, rdb, cb, log, scope, identifier, admitted.LeaseId)
+	return err
+}
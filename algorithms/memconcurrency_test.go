@@ -0,0 +1,111 @@
+package algorithms_test
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/models"
+	"goapp/ratelimit"
+	"testing"
+	"time"
+)
+
+func concurrency(identifier string, limit int, ttl string) *models.PolicySchema {
+	return &models.PolicySchema{Scope: "reports", Identifier: identifier, Limit: limit, Window: ttl, Algorithm: constants.AlgorithmConcurrency}
+}
+
+// acquire takes a lease on the key and fails the test unless the decision matches
+func acquire(t *testing.T, limiter *ratelimit.PolicyLimiter, key string, cost int64, allowed bool) string {
+	t.Helper()
+
+	decision, err := limiter.Allow(context.Background(), key, cost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Allowed != allowed {
+		t.Fatalf("%s : expected allowed %v, got %+v", key, allowed, decision)
+	}
+	if allowed == (decision.LeaseId == "") {
+		t.Fatalf("%s : expected a lease only when allowed, got %+v", key, decision)
+	}
+	return decision.LeaseId
+}
+
+func TestConcurrencyMemLeases(t *testing.T) {
+	ctx := context.Background()
+	limiter := newMemoryLimiter(t, ratelimit.Options{}, concurrency("*", 2, "1m"))
+
+	first := acquire(t, limiter, "reports:a", 1, true)
+	second := acquire(t, limiter, "reports:a", 1, true)
+	acquire(t, limiter, "reports:a", 1, false)
+
+	tests := []struct {
+		name     string
+		leaseId  string
+		released bool
+	}{
+		{name: "held lease", leaseId: first, released: true},
+		{name: "lease released twice", leaseId: first, released: false},
+		{name: "unknown lease", leaseId: "unknown", released: false},
+		{name: "other held lease", leaseId: second, released: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			released, err := limiter.Release(ctx, "reports:a", tt.leaseId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if released != tt.released {
+				t.Fatalf("expected released %v, got %v", tt.released, released)
+			}
+		})
+	}
+
+	// both slots are free again, a cost takes as many slots
+	acquire(t, limiter, "reports:a", 2, true)
+	acquire(t, limiter, "reports:a", 1, false)
+}
+
+func TestConcurrencyMemLeaseExpiry(t *testing.T) {
+	limiter := newMemoryLimiter(t, ratelimit.Options{}, concurrency("*", 1, "1s"))
+
+	leaseId := acquire(t, limiter, "reports:a", 1, true)
+	decision, err := limiter.Allow(context.Background(), "reports:a", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Allowed || decision.RetryAfter != time.Second {
+		t.Fatalf("expected to retry once the lease expires in 1s, got %+v", decision)
+	}
+
+	// a client that never releases its lease frees the slot once the lease expires
+	time.Sleep(1100 * time.Millisecond)
+	acquire(t, limiter, "reports:a", 1, true)
+
+	released, err := limiter.Release(context.Background(), "reports:a", leaseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released {
+		t.Fatal("expected the expired lease to be gone")
+	}
+}
+
+func TestConcurrencyMemKeepsLeasedKeys(t *testing.T) {
+	limiter := newMemoryLimiter(t, ratelimit.Options{MemoryStore: models.MemoryStore{MaxKeys: 1}},
+		concurrency("*", 1, "1m"),
+		&models.PolicySchema{Scope: "api", Identifier: "*", Limit: 10, Window: "1m", Algorithm: constants.AlgorithmFixedWindow},
+	)
+
+	acquire(t, limiter, "reports:a", 1, true)
+
+	// more keys than the cap holds, none of them may evict the held lease
+	for _, key := range []string{"api:a", "api:b", "reports:b", "api:c"} {
+		if _, err := limiter.Allow(context.Background(), key, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	acquire(t, limiter, "reports:a", 1, false)
+	acquire(t, limiter, "reports:b", 1, false)
+}
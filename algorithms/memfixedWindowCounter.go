@@ -4,8 +4,6 @@ import (
 	"context"
 	"goapp/constants"
 	"goapp/models"
-	"goapp/services"
-	"goapp/utils"
 	"sync"
 	"time"
 
@@ -22,37 +20,49 @@ type FixedWindowStore struct {
 type FixedWindow struct {
 	capacity int
 	window   time.Duration
-	tokens   sync.Map
+	tokens   *keyStore
 }
 
-func NewFixedWindowMem(windowStr string, capacity int, log zerolog.Logger) *FixedWindow {
+func NewFixedWindowMem(windowStr string, capacity int, tracker *keyTracker, log zerolog.Logger) *FixedWindow {
 	window, err := time.ParseDuration(windowStr)
 	if err != nil {
 		log.Error().Err(err).Msg("Error parsing the duration")
 	}
 
-	return &FixedWindow{
+	fw := &FixedWindow{
 		capacity: capacity,
 		window:   window,
-		tokens:   sync.Map{},
 	}
+	fw.tokens = newKeyStore(constants.AlgorithmFixedWindow, tracker, fw.windowPassed)
+	return fw
 }
 
-func (fw *FixedWindow) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, scope, identifier string) (*models.LimiterResponse, error) {
-	key := utils.StringBuilder(constants.KeyRateLimit, constants.AlgorithmFixedWindow, scope, identifier)
+// windowPassed reports whether the stored window is over, the next request starts a fresh one anyway
+func (fw *FixedWindow) windowPassed(state any, now time.Time) bool {
+	tokenStore := state.(*FixedWindowStore)
+	tokenStore.mu.Lock()
+	defer tokenStore.mu.Unlock()
+
+	return int(now.UnixNano()/int64(fw.window)) > tokenStore.windowIndex
+}
+
+func (fw *FixedWindow) Close() {
+	fw.tokens.release()
+}
+
+func (fw *FixedWindow) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmFixedWindow, scope, identifier)
 	now := time.Now()
 
 	currentWindowIdx := int(now.UnixNano() / int64(fw.window))
 
-	// fetch the data from the cache
-	val, ok := fw.tokens.Load(key)
-	if !ok {
-		// initialize a new window store
-		val, _ = fw.tokens.LoadOrStore(key, &FixedWindowStore{
+	// fetch the data from the cache, initialize a new window store for unseen keys
+	val := fw.tokens.load(key, now, func() any {
+		return &FixedWindowStore{
 			windowIndex: currentWindowIdx,
 			tokens:      fw.capacity,
-		})
-	}
+		}
+	})
 
 	tokenStore := val.(*FixedWindowStore)
 	tokenStore.mu.Lock()
@@ -63,17 +73,22 @@ func (fw *FixedWindow) Allow(ctx context.Context, rdb *redis.Client, cb *service
 		tokenStore.tokens = fw.capacity
 	}
 
-	if tokenStore.tokens <= 0 {
+	// the window is refilled once it ends, denied requests have to wait until then
+	reset := secondsUntil(time.Duration(int64(currentWindowIdx+1)*int64(fw.window) - now.UnixNano()))
+
+	if int64(tokenStore.tokens) < cost {
 		log.Warn().Str("scope", scope).Msg("Request is rejected, bucket empty")
 		return &models.LimiterResponse{
 			Allowed:         false,
-			RetryAfter:      0,
+			RetryAfter:      reset,
 			RemainingTokens: int64(tokenStore.tokens),
 			TotalTokens:     int64(fw.capacity),
+			Reset:           reset,
+			Window:          secondsUntil(fw.window),
 		}, nil
 	}
 
-	tokenStore.tokens -= 1
+	tokenStore.tokens -= int(cost)
 	log.Debug().Str("scope", scope).Msg("Request is allowed")
 
 	return &models.LimiterResponse{
@@ -81,5 +96,26 @@ func (fw *FixedWindow) Allow(ctx context.Context, rdb *redis.Client, cb *service
 		RetryAfter:      0,
 		RemainingTokens: int64(tokenStore.tokens),
 		TotalTokens:     int64(fw.capacity),
+		Reset:           reset,
+		Window:          secondsUntil(fw.window),
 	}, nil
 }
+
+func (fw *FixedWindow) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmFixedWindow, scope, identifier)
+
+	val, ok := fw.tokens.peek(key)
+	if !ok {
+		return nil
+	}
+
+	tokenStore := val.(*FixedWindowStore)
+	tokenStore.mu.Lock()
+	defer tokenStore.mu.Unlock()
+
+	// a window that has ended since is refilled anyway
+	if int(time.Now().UnixNano()/int64(fw.window)) == tokenStore.windowIndex {
+		tokenStore.tokens = min(fw.capacity, tokenStore.tokens+int(cost))
+	}
+	return nil
+}
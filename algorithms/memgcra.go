@@ -0,0 +1,118 @@
+package algorithms
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/models"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+type GCRAStore struct {
+	tat time.Time
+	mu  sync.Mutex
+}
+
+type GCRA struct {
+	emissionInterval time.Duration
+	tolerance        time.Duration
+	capacity         int64
+	arrivals         *keyStore
+}
+
+func NewGCRAMem(windowStr string, limit, burst int64, tracker *keyTracker, log zerolog.Logger) *GCRA {
+	emissionInterval, tolerance, capacity := gcraParams(windowStr, limit, burst, log)
+
+	g := &GCRA{
+		emissionInterval: emissionInterval,
+		tolerance:        tolerance,
+		capacity:         capacity,
+	}
+	g.arrivals = newKeyStore(constants.AlgorithmGCRA, tracker, g.caughtUp)
+	return g
+}
+
+// caughtUp reports whether the theoretical arrival time is in the past, which is the same as never seen
+func (g *GCRA) caughtUp(state any, now time.Time) bool {
+	arrival := state.(*GCRAStore)
+	arrival.mu.Lock()
+	defer arrival.mu.Unlock()
+
+	return !arrival.tat.After(now)
+}
+
+func (g *GCRA) Close() {
+	g.arrivals.release()
+}
+
+func (g *GCRA) remaining(tat, now time.Time) int64 {
+	if g.emissionInterval <= 0 {
+		return g.capacity
+	}
+	return max(int64((g.tolerance-tat.Sub(now))/g.emissionInterval), 0)
+}
+
+func (g *GCRA) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmGCRA, scope, identifier)
+	now := time.Now()
+
+	val := g.arrivals.load(key, now, func() any {
+		return &GCRAStore{tat: now}
+	})
+
+	arrival := val.(*GCRAStore)
+	arrival.mu.Lock()
+	defer arrival.mu.Unlock()
+
+	tat := arrival.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTat := tat.Add(time.Duration(cost) * g.emissionInterval)
+	allowAt := newTat.Add(-g.tolerance)
+
+	if now.Before(allowAt) {
+		log.Warn().Str("scope", scope).Msg("Request is rejected, arriving ahead of its theoretical arrival time")
+		return &models.LimiterResponse{
+			Allowed:         false,
+			RetryAfter:      secondsUntil(allowAt.Sub(now)),
+			RemainingTokens: g.remaining(tat, now),
+			TotalTokens:     g.capacity,
+			Reset:           secondsUntil(tat.Sub(now)),
+			Window:          secondsUntil(g.tolerance),
+		}, nil
+	}
+
+	arrival.tat = newTat
+	log.Debug().Str("scope", scope).Msg("Request is allowed")
+
+	return &models.LimiterResponse{
+		Allowed:         true,
+		RetryAfter:      0,
+		RemainingTokens: g.remaining(newTat, now),
+		TotalTokens:     g.capacity,
+		Reset:           secondsUntil(newTat.Sub(now)),
+		Window:          secondsUntil(g.tolerance),
+	}, nil
+}
+
+func (g *GCRA) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmGCRA, scope, identifier)
+
+	val, ok := g.arrivals.peek(key)
+	if !ok {
+		return nil
+	}
+
+	arrival := val.(*GCRAStore)
+	arrival.mu.Lock()
+	defer arrival.mu.Unlock()
+
+	// a tat moved back into the past is the same as a caught up one
+	arrival.tat = arrival.tat.Add(-time.Duration(cost) * g.emissionInterval)
+	return nil
+}
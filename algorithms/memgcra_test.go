@@ -0,0 +1,41 @@
+package algorithms_test
+
+import (
+	"goapp/constants"
+	"goapp/models"
+	"goapp/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestGCRAMem(t *testing.T) {
+	// one request every 10s, with room for a burst of 3
+	limiter := newMemoryLimiter(t, ratelimit.Options{},
+		&models.PolicySchema{Scope: "api", Identifier: "*", Limit: 2, Window: "20s", Burst: 3, Algorithm: constants.AlgorithmGCRA},
+	)
+
+	runSteps(t, limiter, "api:a", []step{
+		{name: "first request", cost: 1, allowed: true, remaining: 2},
+		{name: "second request", cost: 1, allowed: true, remaining: 1},
+		{name: "burst used up", cost: 1, allowed: true, remaining: 0},
+		{name: "next emission", cost: 1, allowed: false, remaining: 0, retryAfter: 10 * time.Second},
+	})
+
+	runSteps(t, limiter, "api:b", []step{
+		{name: "whole burst at once", cost: 3, allowed: true, remaining: 0},
+		{name: "cost of two waits for two emissions", cost: 2, allowed: false, remaining: 0, retryAfter: 20 * time.Second},
+	})
+}
+
+func TestGCRAMemWithoutBurst(t *testing.T) {
+	// the burst defaults to the limit
+	limiter := newMemoryLimiter(t, ratelimit.Options{},
+		&models.PolicySchema{Scope: "api", Identifier: "*", Limit: 4, Window: "1m", Algorithm: constants.AlgorithmGCRA},
+	)
+
+	runSteps(t, limiter, "api:a", []step{
+		{name: "cost of three", cost: 3, allowed: true, remaining: 1},
+		{name: "last slot", cost: 1, allowed: true, remaining: 0},
+		{name: "next emission", cost: 1, allowed: false, remaining: 0, retryAfter: 15 * time.Second},
+	})
+}
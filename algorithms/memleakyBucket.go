@@ -2,11 +2,8 @@ package algorithms
 
 import (
 	"context"
-	"errors"
 	"goapp/constants"
 	"goapp/models"
-	"goapp/services"
-	"goapp/utils"
 	"sync"
 	"time"
 
@@ -23,30 +20,42 @@ type LeakyBucketStore struct {
 type LeakyBucket struct {
 	capacity float64
 	leakRate float64
-	tokens   sync.Map
+	tokens   *keyStore
 }
 
-func NewLeakyBucketMem(capacity, leakRate float64, log zerolog.Logger) *LeakyBucket {
-	return &LeakyBucket{
+func NewLeakyBucketMem(capacity, leakRate float64, tracker *keyTracker, log zerolog.Logger) *LeakyBucket {
+	lb := &LeakyBucket{
 		capacity: capacity,
 		leakRate: leakRate,
-		tokens:   sync.Map{},
 	}
+	lb.tokens = newKeyStore(constants.AlgorithmLeakyBucket, tracker, lb.drained)
+	return lb
 }
 
-func (lb *LeakyBucket) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, scope, identifier string) (*models.LimiterResponse, error) {
-	key := utils.StringBuilder(constants.KeyRateLimit, constants.AlgorithmLeakyBucket, scope, identifier)
+// drained reports whether the bucket has leaked everything, at which point it is identical to a fresh one
+func (lb *LeakyBucket) drained(state any, now time.Time) bool {
+	tokenStore := state.(*LeakyBucketStore)
+	tokenStore.mu.Lock()
+	defer tokenStore.mu.Unlock()
+
+	return tokenStore.tokens-(now.Sub(tokenStore.lastLeak).Seconds()*lb.leakRate) <= 0
+}
+
+func (lb *LeakyBucket) Close() {
+	lb.tokens.release()
+}
+
+func (lb *LeakyBucket) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmLeakyBucket, scope, identifier)
 	now := time.Now()
 
-	// Fetch the details from the cache
-	val, ok := lb.tokens.Load(key)
-	if !ok {
-		// allocate a fresh store
-		val, _ = lb.tokens.LoadOrStore(key, &LeakyBucketStore{
+	// Fetch the details from the cache, allocate a fresh store for unseen keys
+	val := lb.tokens.load(key, now, func() any {
+		return &LeakyBucketStore{
 			tokens:   0,
 			lastLeak: now,
-		})
-	}
+		}
+	})
 
 	tokenStore := val.(*LeakyBucketStore)
 	tokenStore.mu.Lock()
@@ -60,23 +69,46 @@ func (lb *LeakyBucket) Allow(ctx context.Context, rdb *redis.Client, cb *service
 	}
 	tokenStore.lastLeak = now
 
-	// check if the bucket is full
-	if tokenStore.tokens >= lb.capacity {
+	// check if the bucket has room for the request
+	if tokenStore.tokens+float64(cost) > lb.capacity {
 		log.Warn().Str("scope", scope).Msg("Request is getting rejected, bucket is full")
+
+		// a request larger than the bucket can never fit, it is asked to come back once the bucket is empty
+		overflow := tokenStore.tokens + min(float64(cost), lb.capacity) - lb.capacity
 		return &models.LimiterResponse{
 			Allowed:         false,
-			RetryAfter:      0,
-			RemainingTokens: int64(tokenStore.tokens),
+			RetryAfter:      secondsUntil(bucketWait(overflow, lb.leakRate)),
+			RemainingTokens: int64(lb.capacity - tokenStore.tokens),
 			TotalTokens:     int64(lb.capacity),
-		}, errors.New("Request is getting rejected")
+			Reset:           secondsUntil(bucketWait(tokenStore.tokens, lb.leakRate)),
+			Window:          bucketWindow(lb.capacity, lb.leakRate),
+		}, nil
 	}
 
-	tokenStore.tokens += 1
+	tokenStore.tokens += float64(cost)
 
 	return &models.LimiterResponse{
 		Allowed:         true,
 		RetryAfter:      0,
-		RemainingTokens: int64(tokenStore.tokens),
+		RemainingTokens: int64(lb.capacity - tokenStore.tokens),
 		TotalTokens:     int64(lb.capacity),
+		Reset:           secondsUntil(bucketWait(tokenStore.tokens, lb.leakRate)),
+		Window:          bucketWindow(lb.capacity, lb.leakRate),
 	}, nil
 }
+
+func (lb *LeakyBucket) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmLeakyBucket, scope, identifier)
+
+	val, ok := lb.tokens.peek(key)
+	if !ok {
+		return nil
+	}
+
+	tokenStore := val.(*LeakyBucketStore)
+	tokenStore.mu.Lock()
+	defer tokenStore.mu.Unlock()
+
+	tokenStore.tokens = max(0, tokenStore.tokens-float64(cost))
+	return nil
+}
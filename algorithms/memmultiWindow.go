@@ -0,0 +1,200 @@
+package algorithms
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/models"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+type ruleWindow struct {
+	currentCnt  int64
+	previousCnt int64
+	windowStart time.Time
+}
+
+// shift moves the window forward when it has passed and returns how far into the current window now is
+func (w *ruleWindow) shift(now time.Time, window time.Duration) time.Duration {
+	elapsed := now.Sub(w.windowStart)
+	if elapsed >= window {
+		shift := elapsed / window
+
+		if shift >= 2 {
+			w.previousCnt = 0
+		} else {
+			w.previousCnt = w.currentCnt
+		}
+
+		w.currentCnt = 0
+		w.windowStart = w.windowStart.Add(shift * window)
+		elapsed = now.Sub(w.windowStart)
+	}
+	return elapsed
+}
+
+type windowDecision struct {
+	allowed   bool
+	binding   int
+	remaining int64
+	retry     time.Duration
+	reset     time.Duration
+}
+
+// windowResponse reports the decision against the binding rule
+func windowResponse(rules []windowRule, decision windowDecision) *models.LimiterResponse {
+	response := &models.LimiterResponse{
+		Allowed:         decision.allowed,
+		RetryAfter:      secondsUntil(decision.retry),
+		RemainingTokens: decision.remaining,
+		Reset:           secondsUntil(decision.reset),
+	}
+	if decision.binding >= 0 {
+		response.Rule = rules[decision.binding].name
+		response.TotalTokens = rules[decision.binding].capacity
+		response.Window = secondsUntil(rules[decision.binding].window)
+	}
+	return response
+}
+
+// evaluateWindows checks the request against every rule without consuming anything,
+// the binding rule is the one keeping the request out the longest or, when allowed, the one with the least room left
+func evaluateWindows(windows []*ruleWindow, rules []windowRule, now time.Time, cost int64) windowDecision {
+	decision := windowDecision{allowed: true, binding: -1}
+	bindingRemaining := math.Inf(1)
+
+	for i, rule := range rules {
+		elapsed := windows[i].shift(now, rule.window)
+
+		weight := float64(rule.window-elapsed) / float64(rule.window)
+		effectiveCnt := float64(windows[i].currentCnt) + weight*float64(windows[i].previousCnt)
+
+		if effectiveCnt+float64(cost) > float64(rule.capacity) {
+			retryAfter := slidingRetryAfter(float64(windows[i].currentCnt), float64(windows[i].previousCnt), elapsed, rule.window, float64(rule.capacity), float64(cost))
+
+			if decision.allowed || retryAfter > decision.retry {
+				decision.binding = i
+				decision.retry = retryAfter
+				decision.reset = slidingReset(windows[i].currentCnt, windows[i].previousCnt, elapsed, rule.window)
+				bindingRemaining = math.Max(0, float64(rule.capacity)-effectiveCnt)
+			}
+			decision.allowed = false
+		} else if decision.allowed {
+			if remaining := float64(rule.capacity) - effectiveCnt - float64(cost); remaining < bindingRemaining {
+				decision.binding = i
+				// the request is consumed from the rule once every rule allowed it
+				decision.reset = slidingReset(windows[i].currentCnt+cost, windows[i].previousCnt, elapsed, rule.window)
+				bindingRemaining = remaining
+			}
+		}
+	}
+
+	if decision.binding >= 0 {
+		decision.remaining = int64(bindingRemaining)
+	}
+	return decision
+}
+
+// consumeWindows charges the request to every rule, only called once all of them allowed it
+func consumeWindows(windows []*ruleWindow, cost int64) {
+	for _, window := range windows {
+		window.currentCnt += cost
+	}
+}
+
+type MultiWindowStore struct {
+	windows []ruleWindow
+	mu      sync.Mutex
+}
+
+type MultiWindow struct {
+	rules   []windowRule
+	windows *keyStore
+}
+
+func NewMultiWindowMem(rules []models.LimitRule, tracker *keyTracker, log zerolog.Logger) *MultiWindow {
+	mw := &MultiWindow{
+		rules: parseRules(rules, log),
+	}
+	mw.windows = newKeyStore(constants.AlgorithmMultiWindow, tracker, mw.agedOut)
+	return mw
+}
+
+// agedOut reports whether every rule window is old enough to no longer weigh in
+func (mw *MultiWindow) agedOut(state any, now time.Time) bool {
+	windowStore := state.(*MultiWindowStore)
+	windowStore.mu.Lock()
+	defer windowStore.mu.Unlock()
+
+	for i, rule := range mw.rules {
+		if now.Sub(windowStore.windows[i].windowStart) < 2*rule.window {
+			return false
+		}
+	}
+	return true
+}
+
+func (mw *MultiWindow) Close() {
+	mw.windows.release()
+}
+
+func (mw *MultiWindow) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmMultiWindow, scope, identifier)
+	now := time.Now()
+
+	val := mw.windows.load(key, now, func() any {
+		windows := make([]ruleWindow, len(mw.rules))
+		for i := range windows {
+			windows[i].windowStart = now
+		}
+		return &MultiWindowStore{windows: windows}
+	})
+
+	windowStore := val.(*MultiWindowStore)
+	windowStore.mu.Lock()
+	defer windowStore.mu.Unlock()
+
+	windows := make([]*ruleWindow, len(windowStore.windows))
+	for i := range windowStore.windows {
+		windows[i] = &windowStore.windows[i]
+	}
+
+	decision := evaluateWindows(windows, mw.rules, now, cost)
+
+	response := windowResponse(mw.rules, decision)
+
+	if !decision.allowed {
+		log.Warn().Str("scope", scope).Str("rule", response.Rule).Msg("Request is rejected, rule threshold exceeded")
+		return response, nil
+	}
+
+	consumeWindows(windows, cost)
+	log.Debug().Str("scope", scope).Msg("Request is allowed")
+
+	return response, nil
+}
+
+func (mw *MultiWindow) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmMultiWindow, scope, identifier)
+	now := time.Now()
+
+	val, ok := mw.windows.peek(key)
+	if !ok {
+		return nil
+	}
+
+	windowStore := val.(*MultiWindowStore)
+	windowStore.mu.Lock()
+	defer windowStore.mu.Unlock()
+
+	for i, rule := range mw.rules {
+		window := &windowStore.windows[i]
+		window.shift(now, rule.window)
+		window.currentCnt, window.previousCnt = refundCounts(window.currentCnt, window.previousCnt, cost)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package algorithms_test
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/models"
+	"goapp/ratelimit"
+	"testing"
+)
+
+func TestMultiWindowMemAllOrNone(t *testing.T) {
+	limiter := newMemoryLimiter(t, ratelimit.Options{},
+		&models.PolicySchema{Scope: "api", Identifier: "*", Algorithm: constants.AlgorithmMultiWindow, Rules: []models.LimitRule{
+			{Name: "minute", Limit: 4, Window: "1m"},
+			{Name: "hour", Limit: 5, Window: "1h"},
+		}},
+	)
+
+	tests := []struct {
+		name      string
+		cost      int64
+		allowed   bool
+		remaining int64
+		rule      string
+	}{
+		{name: "charged to both rules, the minute has the least room", cost: 3, allowed: true, remaining: 1, rule: "minute"},
+		// the hour has room for it, it must not be charged when the minute rejects
+		{name: "minute rejects", cost: 2, allowed: false, remaining: 1, rule: "minute"},
+		{name: "hour still holds the room the rejected request left", cost: 1, allowed: true, remaining: 0, rule: "minute"},
+		{name: "minute used up", cost: 1, allowed: false, remaining: 0, rule: "minute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := limiter.Allow(context.Background(), "api:a", tt.cost)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision.Allowed != tt.allowed || decision.Remaining != tt.remaining || decision.Rule != tt.rule {
+				t.Fatalf("expected allowed %v with %d remaining on rule %s, got %+v", tt.allowed, tt.remaining, tt.rule, decision)
+			}
+			if !tt.allowed && decision.RetryAfter <= 0 {
+				t.Fatalf("expected a rejected request to be told when to retry, got %+v", decision)
+			}
+		})
+	}
+}
+
+func TestMultiWindowMemReportsBindingRule(t *testing.T) {
+	limiter := newMemoryLimiter(t, ratelimit.Options{},
+		&models.PolicySchema{Scope: "api", Identifier: "*", Algorithm: constants.AlgorithmMultiWindow, Rules: []models.LimitRule{
+			{Name: "burst", Limit: 10, Window: "1s"},
+			{Name: "day", Limit: 3, Window: "24h"},
+		}},
+	)
+
+	decision, err := limiter.Allow(context.Background(), "api:a", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decision.Allowed || decision.Rule != "day" || decision.Limit != 3 || decision.Remaining != 0 {
+		t.Fatalf("expected the day to bind with nothing left, got %+v", decision)
+	}
+
+	decision, err = limiter.Allow(context.Background(), "api:a", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Allowed || decision.Rule != "day" {
+		t.Fatalf("expected the day to reject the request, got %+v", decision)
+	}
+}
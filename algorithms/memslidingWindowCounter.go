@@ -4,8 +4,6 @@ import (
 	"context"
 	"goapp/constants"
 	"goapp/models"
-	"goapp/services"
-	"goapp/utils"
 	"sync"
 	"time"
 
@@ -23,41 +21,34 @@ type SlidingWindowStore struct {
 type SlidingWindow struct {
 	capacity int
 	window   time.Duration
-	tokens   sync.Map
+	tokens   *keyStore
 }
 
-func NewSlidingWindowMem(windowStr string, capacity int, log zerolog.Logger) *SlidingWindow {
+func NewSlidingWindowMem(windowStr string, capacity int, tracker *keyTracker, log zerolog.Logger) *SlidingWindow {
 	window, err := time.ParseDuration(windowStr)
 	if err != nil {
 		log.Error().Err(err).Msg("Error parsing the duration")
 	}
 
-	return &SlidingWindow{
+	sw := &SlidingWindow{
 		capacity: capacity,
 		window:   window,
-		tokens:   sync.Map{},
 	}
+	sw.tokens = newKeyStore(constants.AlgorithmSlidingWindow, tracker, sw.agedOut)
+	return sw
 }
 
-func (sw *SlidingWindow) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, scope, identifier string) (*models.LimiterResponse, error) {
-	key := utils.StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingWindow, scope, identifier)
-	now := time.Now()
-
-	// fetch the data from the cache
-	val, ok := sw.tokens.Load(key)
-	if !ok {
-		// allocate and initialize new sliding window state
-		val, _ = sw.tokens.LoadOrStore(key, &SlidingWindowStore{
-			windowStart: now,
-			currentCnt:  0,
-			previousCnt: 0,
-		})
-	}
-
-	tokens := val.(*SlidingWindowStore)
+// agedOut reports whether both the current and the previous window are over, so no count is weighted anymore
+func (sw *SlidingWindow) agedOut(state any, now time.Time) bool {
+	tokens := state.(*SlidingWindowStore)
 	tokens.mu.Lock()
 	defer tokens.mu.Unlock()
 
+	return now.Sub(tokens.windowStart) >= 2*sw.window
+}
+
+// shift moves the window forward when it has passed and returns how far into the current window now is
+func (sw *SlidingWindow) shift(tokens *SlidingWindowStore, now time.Time) time.Duration {
 	elapsed := now.Sub(tokens.windowStart)
 
 	if elapsed >= sw.window {
@@ -73,28 +64,75 @@ func (sw *SlidingWindow) Allow(ctx context.Context, rdb *redis.Client, cb *servi
 		tokens.windowStart = tokens.windowStart.Add(time.Duration(shift) * sw.window)
 		elapsed = now.Sub(tokens.windowStart)
 	}
+	return elapsed
+}
+
+func (sw *SlidingWindow) Close() {
+	sw.tokens.release()
+}
+
+func (sw *SlidingWindow) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingWindow, scope, identifier)
+	now := time.Now()
+
+	// fetch the data from the cache, allocate and initialize new sliding window state for unseen keys
+	val := sw.tokens.load(key, now, func() any {
+		return &SlidingWindowStore{
+			windowStart: now,
+			currentCnt:  0,
+			previousCnt: 0,
+		}
+	})
+
+	tokens := val.(*SlidingWindowStore)
+	tokens.mu.Lock()
+	defer tokens.mu.Unlock()
+
+	elapsed := sw.shift(tokens, now)
 
 	// weightage is the percentage of the current window that has elapsed
 	weight := float64(sw.window-elapsed) / float64(sw.window)
 	effectiveCnt := float64(tokens.currentCnt) + weight*float64(tokens.previousCnt)
 
-	if effectiveCnt >= float64(sw.capacity) {
+	if effectiveCnt+float64(cost) > float64(sw.capacity) {
 		log.Warn().Str("scope", scope).Msg("Request is rejected, threshold exceeded")
 		return &models.LimiterResponse{
 			Allowed:         false,
-			RetryAfter:      0,
-			RemainingTokens: int64(tokens.currentCnt),
+			RetryAfter:      secondsUntil(slidingRetryAfter(float64(tokens.currentCnt), float64(tokens.previousCnt), elapsed, sw.window, float64(sw.capacity), float64(cost))),
+			RemainingTokens: int64(max(float64(sw.capacity)-effectiveCnt, 0)),
 			TotalTokens:     int64(sw.capacity),
+			Reset:           secondsUntil(slidingReset(int64(tokens.currentCnt), int64(tokens.previousCnt), elapsed, sw.window)),
+			Window:          secondsUntil(sw.window),
 		}, nil
 	}
 
-	tokens.currentCnt += 1
+	tokens.currentCnt += int(cost)
 	log.Debug().Str("scope", scope).Msg("Request is allowed")
 
 	return &models.LimiterResponse{
 		Allowed:         true,
 		RetryAfter:      0,
-		RemainingTokens: int64(tokens.currentCnt),
+		RemainingTokens: int64(float64(sw.capacity) - effectiveCnt - float64(cost)),
 		TotalTokens:     int64(sw.capacity),
+		Reset:           secondsUntil(slidingReset(int64(tokens.currentCnt), int64(tokens.previousCnt), elapsed, sw.window)),
+		Window:          secondsUntil(sw.window),
 	}, nil
 }
+
+func (sw *SlidingWindow) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingWindow, scope, identifier)
+
+	val, ok := sw.tokens.peek(key)
+	if !ok {
+		return nil
+	}
+
+	tokens := val.(*SlidingWindowStore)
+	tokens.mu.Lock()
+	defer tokens.mu.Unlock()
+
+	sw.shift(tokens, time.Now())
+	currentCnt, previousCnt := refundCounts(int64(tokens.currentCnt), int64(tokens.previousCnt), cost)
+	tokens.currentCnt, tokens.previousCnt = int(currentCnt), int(previousCnt)
+	return nil
+}
@@ -0,0 +1,163 @@
+package algorithms
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/models"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+const initialLogSize = 16
+
+// SlidingLogStore is a ring buffer holding the timestamps of the requests inside the window, oldest first
+type SlidingLogStore struct {
+	entries []int64
+	head    int
+	count   int
+	mu      sync.Mutex
+}
+
+func (s *SlidingLogStore) at(i int) int64 {
+	return s.entries[(s.head+i)%len(s.entries)]
+}
+
+// prune drops every request logged at or before the threshold
+func (s *SlidingLogStore) prune(threshold int64) {
+	for s.count > 0 && s.entries[s.head] <= threshold {
+		s.head = (s.head + 1) % len(s.entries)
+		s.count--
+	}
+}
+
+func (s *SlidingLogStore) push(timestamp int64, n, capacity int) {
+	if s.count+n > len(s.entries) {
+		s.grow(min(capacity, max(2*len(s.entries), s.count+n)))
+	}
+
+	for i := 0; i < n; i++ {
+		s.entries[(s.head+s.count)%len(s.entries)] = timestamp
+		s.count++
+	}
+}
+
+func (s *SlidingLogStore) grow(size int) {
+	grown := make([]int64, size)
+	for i := 0; i < s.count; i++ {
+		grown[i] = s.at(i)
+	}
+	s.entries = grown
+	s.head = 0
+}
+
+type SlidingWindowLog struct {
+	capacity int
+	window   time.Duration
+	logs     *keyStore
+}
+
+func NewSlidingWindowLogMem(windowStr string, capacity int, tracker *keyTracker, log zerolog.Logger) *SlidingWindowLog {
+	window, err := time.ParseDuration(windowStr)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the duration")
+	}
+
+	sl := &SlidingWindowLog{
+		capacity: capacity,
+		window:   window,
+	}
+	sl.logs = newKeyStore(constants.AlgorithmSlidingLog, tracker, sl.emptied)
+	return sl
+}
+
+// emptied reports whether every logged request has left the window
+func (sl *SlidingWindowLog) emptied(state any, now time.Time) bool {
+	logStore := state.(*SlidingLogStore)
+	logStore.mu.Lock()
+	defer logStore.mu.Unlock()
+
+	return logStore.count == 0 || logStore.at(logStore.count-1) <= now.Add(-sl.window).UnixNano()
+}
+
+// reset is the time until the newest logged request has left the window and the whole limit is available again
+func (sl *SlidingWindowLog) reset(logStore *SlidingLogStore, now time.Time) int64 {
+	if logStore.count == 0 {
+		return 0
+	}
+	return secondsUntil(time.Duration(logStore.at(logStore.count-1) + int64(sl.window) - now.UnixNano()))
+}
+
+func (sl *SlidingWindowLog) Close() {
+	sl.logs.release()
+}
+
+func (sl *SlidingWindowLog) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingLog, scope, identifier)
+	now := time.Now()
+
+	val := sl.logs.load(key, now, func() any {
+		return &SlidingLogStore{
+			entries: make([]int64, min(sl.capacity, initialLogSize)),
+		}
+	})
+
+	logStore := val.(*SlidingLogStore)
+	logStore.mu.Lock()
+	defer logStore.mu.Unlock()
+
+	// Drop timestamps older than the window
+	logStore.prune(now.Add(-sl.window).UnixNano())
+
+	requested := int(cost)
+	if logStore.count+requested > sl.capacity {
+		log.Warn().Str("scope", scope).Msg("Request is rejected, window is full")
+
+		// the request fits once enough of the oldest requests have left the window
+		retryAfter := sl.window
+		if requested <= sl.capacity {
+			oldest := logStore.at(logStore.count + requested - sl.capacity - 1)
+			retryAfter = time.Duration(oldest + int64(sl.window) - now.UnixNano())
+		}
+
+		return &models.LimiterResponse{
+			Allowed:         false,
+			RetryAfter:      secondsUntil(retryAfter),
+			RemainingTokens: int64(sl.capacity - logStore.count),
+			TotalTokens:     int64(sl.capacity),
+			Reset:           sl.reset(logStore, now),
+			Window:          secondsUntil(sl.window),
+		}, nil
+	}
+
+	logStore.push(now.UnixNano(), requested, sl.capacity)
+	log.Debug().Str("scope", scope).Msg("Request is allowed")
+
+	return &models.LimiterResponse{
+		Allowed:         true,
+		RetryAfter:      0,
+		RemainingTokens: int64(sl.capacity - logStore.count),
+		TotalTokens:     int64(sl.capacity),
+		Reset:           sl.reset(logStore, now),
+		Window:          secondsUntil(sl.window),
+	}, nil
+}
+
+func (sl *SlidingWindowLog) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingLog, scope, identifier)
+
+	val, ok := sl.logs.peek(key)
+	if !ok {
+		return nil
+	}
+
+	logStore := val.(*SlidingLogStore)
+	logStore.mu.Lock()
+	defer logStore.mu.Unlock()
+
+	// the refunded request is among the newest ones logged, any of them frees the same room
+	logStore.count -= min(logStore.count, int(cost))
+	return nil
+}
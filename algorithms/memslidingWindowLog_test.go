@@ -0,0 +1,94 @@
+package algorithms_test
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/models"
+	"goapp/ratelimit"
+	"goapp/services"
+	"goapp/store"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// newMemoryLimiter runs the decision path of the service in memory, from the policy cache to the algorithms
+func newMemoryLimiter(t *testing.T, options ratelimit.Options, policies ...*models.PolicySchema) *ratelimit.PolicyLimiter {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cache := services.NewCache(store.NewMemoryPolicyStore(policies...))
+	cache.LoadCache(ctx, zerolog.Nop())
+
+	backends := ratelimit.NewBackends(ctx, options)
+	return ratelimit.New(backends.Memory(), cache)
+}
+
+// step is one request of a sequence sent to the same key, the durations are whole seconds as the algorithms round up
+type step struct {
+	name       string
+	cost       int64
+	allowed    bool
+	remaining  int64
+	retryAfter time.Duration
+}
+
+func runSteps(t *testing.T, limiter *ratelimit.PolicyLimiter, key string, steps []step) {
+	t.Helper()
+
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := limiter.Allow(context.Background(), key, tt.cost)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision.Allowed != tt.allowed {
+				t.Fatalf("expected allowed %v, got %+v", tt.allowed, decision)
+			}
+			if decision.Remaining != tt.remaining {
+				t.Fatalf("expected %d remaining, got %d", tt.remaining, decision.Remaining)
+			}
+			if decision.RetryAfter != tt.retryAfter {
+				t.Fatalf("expected to retry after %s, got %s", tt.retryAfter, decision.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestSlidingWindowLogMem(t *testing.T) {
+	limiter := newMemoryLimiter(t, ratelimit.Options{},
+		&models.PolicySchema{Scope: "api", Identifier: "*", Limit: 3, Window: "10s", Algorithm: constants.AlgorithmSlidingLog},
+	)
+
+	runSteps(t, limiter, "api:a", []step{
+		{name: "first request", cost: 1, allowed: true, remaining: 2},
+		{name: "cost of two", cost: 2, allowed: true, remaining: 0},
+		{name: "full window", cost: 1, allowed: false, remaining: 0, retryAfter: 10 * time.Second},
+		{name: "cost above the limit waits for the whole window", cost: 4, allowed: false, remaining: 0, retryAfter: 10 * time.Second},
+	})
+
+	// every key keeps its own log
+	runSteps(t, limiter, "api:b", []step{
+		{name: "other key", cost: 3, allowed: true, remaining: 0},
+	})
+}
+
+func TestSlidingWindowLogMemSlides(t *testing.T) {
+	limiter := newMemoryLimiter(t, ratelimit.Options{},
+		&models.PolicySchema{Scope: "api", Identifier: "*", Limit: 2, Window: "1s", Algorithm: constants.AlgorithmSlidingLog},
+	)
+
+	runSteps(t, limiter, "api:a", []step{
+		{name: "first request", cost: 1, allowed: true, remaining: 1},
+		{name: "second request", cost: 1, allowed: true, remaining: 0},
+		{name: "full window", cost: 1, allowed: false, remaining: 0, retryAfter: time.Second},
+	})
+
+	// the oldest requests have left the window, not merely a new window started
+	time.Sleep(1100 * time.Millisecond)
+
+	runSteps(t, limiter, "api:a", []step{
+		{name: "room once the requests left the window", cost: 2, allowed: true, remaining: 0},
+	})
+}
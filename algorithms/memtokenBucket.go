@@ -2,11 +2,8 @@ package algorithms
 
 import (
 	"context"
-	"errors"
 	"goapp/constants"
 	"goapp/models"
-	"goapp/services"
-	"goapp/utils"
 	"sync"
 	"time"
 
@@ -23,32 +20,44 @@ type TokenBucketStore struct {
 type TokenBucket struct {
 	capacity float64
 	fillRate float64
-	tokens   sync.Map
+	tokens   *keyStore
 }
 
-func NewTokenBucketMem(capacity, fillRate float64, log zerolog.Logger) *TokenBucket {
-	return &TokenBucket{
+func NewTokenBucketMem(capacity, fillRate float64, tracker *keyTracker, log zerolog.Logger) *TokenBucket {
+	tb := &TokenBucket{
 		capacity: capacity,
 		fillRate: fillRate,
-		tokens:   sync.Map{},
 	}
+	tb.tokens = newKeyStore(constants.AlgorithmTokenBucket, tracker, tb.refilled)
+	return tb
 }
 
-func (tb *TokenBucket) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, scope, identifier string) (*models.LimiterResponse, error) {
+// refilled reports whether the bucket is full again, at which point it is identical to a fresh one
+func (tb *TokenBucket) refilled(state any, now time.Time) bool {
+	tokenStore := state.(*TokenBucketStore)
+	tokenStore.mu.Lock()
+	defer tokenStore.mu.Unlock()
+
+	return tokenStore.tokens+(now.Sub(tokenStore.lastFill).Seconds()*tb.fillRate) >= tb.capacity
+}
+
+func (tb *TokenBucket) Close() {
+	tb.tokens.release()
+}
+
+func (tb *TokenBucket) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
 	// make the key
-	key := utils.StringBuilder(constants.KeyRateLimit, constants.AlgorithmTokenBucket, scope, identifier)
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmTokenBucket, scope, identifier)
 	now := time.Now()
 
-	// Fetch from the cache
-	val, _ := tb.tokens.Load(key)
-	if val == nil {
-		// create a new store if this key hasn't been seen before
+	// Fetch from the cache, create a new store if this key hasn't been seen before
+	val := tb.tokens.load(key, now, func() any {
 		log.Debug().Msg("Token bucket not found in cache, creating new one")
-		val, _ = tb.tokens.LoadOrStore(key, &TokenBucketStore{
+		return &TokenBucketStore{
 			tokens:   tb.capacity,
 			lastFill: now,
-		})
-	}
+		}
+	})
 
 	tokenStore := val.(*TokenBucketStore)
 	tokenStore.mu.Lock()
@@ -62,23 +71,46 @@ func (tb *TokenBucket) Allow(ctx context.Context, rdb *redis.Client, cb *service
 
 	tokenStore.lastFill = now
 
-	// check if the bucket is empty
-	if tokenStore.tokens < 1 {
+	// check if the bucket holds enough tokens for the request
+	if tokenStore.tokens < float64(cost) {
 		log.Warn().Str("scope", scope).Msg("Request is getting rejected, bucket is empty")
+
+		// a request larger than the bucket can never fit, it is asked to come back once the bucket is full
+		missing := min(float64(cost), tb.capacity) - tokenStore.tokens
 		return &models.LimiterResponse{
 			Allowed:         false,
-			RetryAfter:      0,
+			RetryAfter:      secondsUntil(bucketWait(missing, tb.fillRate)),
 			RemainingTokens: int64(tokenStore.tokens),
 			TotalTokens:     int64(tb.capacity),
-		}, errors.New("Request is getting rejected")
+			Reset:           secondsUntil(bucketWait(tb.capacity-tokenStore.tokens, tb.fillRate)),
+			Window:          bucketWindow(tb.capacity, tb.fillRate),
+		}, nil
 	}
 
-	tokenStore.tokens -= 1
+	tokenStore.tokens -= float64(cost)
 
 	return &models.LimiterResponse{
 		Allowed:         true,
 		RetryAfter:      0,
 		RemainingTokens: int64(tokenStore.tokens),
 		TotalTokens:     int64(tb.capacity),
+		Reset:           secondsUntil(bucketWait(tb.capacity-tokenStore.tokens, tb.fillRate)),
+		Window:          bucketWindow(tb.capacity, tb.fillRate),
 	}, nil
 }
+
+func (tb *TokenBucket) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	key := StringBuilder(constants.KeyRateLimit, constants.AlgorithmTokenBucket, scope, identifier)
+
+	val, ok := tb.tokens.peek(key)
+	if !ok {
+		return nil
+	}
+
+	tokenStore := val.(*TokenBucketStore)
+	tokenStore.mu.Lock()
+	defer tokenStore.mu.Unlock()
+
+	tokenStore.tokens = min(tb.capacity, tokenStore.tokens+float64(cost))
+	return nil
+}
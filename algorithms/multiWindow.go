@@ -0,0 +1,166 @@
+package algorithms
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/lua"
+	"goapp/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+type windowRule struct {
+	name     string
+	capacity int64
+	window   time.Duration
+}
+
+func parseRules(rules []models.LimitRule, log zerolog.Logger) []windowRule {
+	parsed := make([]windowRule, 0, len(rules))
+	names := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		window, err := time.ParseDuration(rule.Window)
+		if err != nil || window <= 0 {
+			log.Error().Err(err).Str("window", rule.Window).Msg("Error parsing the rule window, skipping the rule")
+			continue
+		}
+
+		// the name ends up in the storage key, so it has to be unique within the policy
+		name := rule.Name
+		if name == "" || names[name] {
+			name = rule.Window + "#" + strconv.Itoa(i)
+		}
+		names[name] = true
+
+		parsed = append(parsed, windowRule{
+			name:     name,
+			capacity: int64(rule.Limit),
+			window:   window,
+		})
+	}
+	return parsed
+}
+
+// slidingRetryAfter is the time until the weighted count of a sliding window leaves room for the request,
+// first the previous window fades out and, if that is not enough, the current one has to become the previous one
+func slidingRetryAfter(currentCnt, previousCnt float64, elapsed, window time.Duration, capacity, requested float64) time.Duration {
+	if requested > capacity {
+		return window
+	}
+
+	if currentCnt+requested <= capacity {
+		return time.Duration(float64(window)*(1-((capacity-currentCnt-requested)/previousCnt))) - elapsed
+	}
+
+	return (window - elapsed) + time.Duration(float64(window)*(1-((capacity-requested)/currentCnt)))
+}
+
+// slidingReset is the time until every request counted by a sliding window has faded out of the weighted count
+func slidingReset(currentCnt, previousCnt int64, elapsed, window time.Duration) time.Duration {
+	if currentCnt > 0 {
+		return 2*window - elapsed
+	}
+	if previousCnt > 0 {
+		return window - elapsed
+	}
+	return 0
+}
+
+// refundCounts takes the cost back from the current window of a sliding counter, or from the previous one for the
+// part the current window does not hold because it has moved on since the request was counted
+func refundCounts(currentCnt, previousCnt, cost int64) (int64, int64) {
+	fromCurrent := min(currentCnt, cost)
+	return currentCnt - fromCurrent, max(0, previousCnt-(cost-fromCurrent))
+}
+
+// MultiWindowRedis checks every rule of the policy in a single script so the request consumes from all of them or none
+type MultiWindowRedis struct {
+	rules []windowRule
+}
+
+func NewMultiWindow(rules []models.LimitRule, log zerolog.Logger) *MultiWindowRedis {
+	return &MultiWindowRedis{
+		rules: parseRules(rules, log),
+	}
+}
+
+func windowKey(scope, identifier string, rule windowRule) string {
+	return StringBuilder(constants.KeyRateLimit, constants.AlgorithmMultiWindow, scope, identifier) + ":" + rule.name
+}
+
+// runWindowScript checks the request against every key and rule pair in a single script,
+// keys are only charged when all of them allow the request
+func runWindowScript(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, keys []string, rules []windowRule, cost int64) (windowDecision, error) {
+	args := []any{time.Now().UnixMilli(), cost}
+	for _, rule := range rules {
+		args = append(args, rule.capacity, rule.window.Milliseconds())
+	}
+
+	mwScript := redis.NewScript(lua.GetMultiWindowScript())
+
+	results, err := cb.Cb.Execute(func() (any, error) {
+		results, err := mwScript.Run(ctx, rdb, keys, args...).Result()
+		return results, err
+	})
+	if err != nil {
+		return windowDecision{}, err
+	}
+
+	values, err := parseScriptValues(results, 5)
+	if err != nil {
+		return windowDecision{}, err
+	}
+
+	// lua tables are 1 indexed
+	binding := int(values[1]) - 1
+	if binding >= len(rules) {
+		binding = -1
+	}
+
+	return windowDecision{
+		allowed:   values[0] == 1,
+		binding:   binding,
+		remaining: values[2],
+		retry:     time.Duration(values[3]) * time.Millisecond,
+		reset:     time.Duration(values[4]) * time.Millisecond,
+	}, nil
+}
+
+func (mw *MultiWindowRedis) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	keys := make([]string, 0, len(mw.rules))
+	for _, rule := range mw.rules {
+		keys = append(keys, windowKey(scope, identifier, rule))
+	}
+
+	decision, err := runWindowScript(ctx, rdb, cb, keys, mw.rules, cost)
+	if err != nil {
+		log.Error().Err(err).Msg("Error calling the multi window script, rejecting the request")
+		return &models.LimiterResponse{
+			Allowed:         false,
+			RetryAfter:      0,
+			RemainingTokens: 0,
+		}, err
+	}
+
+	response := windowResponse(mw.rules, decision)
+
+	if !response.Allowed {
+		log.Warn().Str("scope", scope).Str("rule", response.Rule).Msg("Request is rejected, rule threshold exceeded")
+	}
+
+	return response, nil
+}
+
+func (mw *MultiWindowRedis) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	keys := make([]string, 0, len(mw.rules))
+	args := []any{time.Now().UnixMilli(), cost}
+	for _, rule := range mw.rules {
+		keys = append(keys, windowKey(scope, identifier, rule))
+		args = append(args, rule.capacity, rule.window.Milliseconds())
+	}
+
+	return runRefundScript(ctx, rdb, cb, lua.GetSlidingWindowRefundScript(), keys, args...)
+}
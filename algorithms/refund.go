@@ -0,0 +1,43 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"goapp/models"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+var ErrRefundUnsupported = errors.New("the limiter cannot hand back a cost")
+
+// Refunder is implemented by the limiters that can hand back the cost of a request they admitted, so that checks
+// which have to pass together can be rolled back when one of them rejects the request. A window that has ended
+// since the request was admitted keeps nothing to hand back
+type Refunder interface {
+	Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error
+}
+
+// RefundCost hands the cost of the admitted decision back to the limiter, rejected decisions charged nothing
+func RefundCost(ctx context.Context, limiter RateLimiter, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	if admitted == nil || !admitted.Allowed {
+		return nil
+	}
+
+	refunder, ok := limiter.(Refunder)
+	if !ok {
+		return ErrRefundUnsupported
+	}
+	return refunder.Refund(ctx, rdb, cb, log, scope, identifier, cost, admitted)
+}
+
+// runRefundScript runs a refund script through the circuit breaker, the result only tells whether the key was found
+func runRefundScript(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, script string, keys []string, args ...any) error {
+	refundScript := redis.NewScript(script)
+
+	_, err := cb.Cb.Execute(func() (any, error) {
+		results, err := refundScript.Run(ctx, rdb, keys, args...).Result()
+		return results, err
+	})
+	return err
+}
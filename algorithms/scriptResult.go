@@ -0,0 +1,23 @@
+package algorithms
+
+import "fmt"
+
+// parseScriptValues decodes the integer table returned by the lua scripts,
+// redis hands lua numbers back as integers so flags arrive as 0 or 1
+func parseScriptValues(results any, count int) ([]int64, error) {
+	values, ok := results.([]any)
+	if !ok || len(values) < count {
+		return nil, fmt.Errorf("unexpected script result : %v", results)
+	}
+
+	parsed := make([]int64, count)
+	for i := 0; i < count; i++ {
+		value, ok := values[i].(int64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value at position %d in script result : %v", i, values[i])
+		}
+		parsed[i] = value
+	}
+
+	return parsed, nil
+}
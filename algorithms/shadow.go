@@ -0,0 +1,76 @@
+package algorithms
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/metrics"
+	"goapp/models"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// shadowLimiter runs a policy in dry-run, the decision is recorded but the request is always let through
+type shadowLimiter struct {
+	base   RateLimiter
+	policy string
+	algo   string
+}
+
+func newShadowLimiter(base RateLimiter, policy, algo string) *shadowLimiter {
+	return &shadowLimiter{
+		base:   base,
+		policy: policy,
+		algo:   algo,
+	}
+}
+
+func (sl *shadowLimiter) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	response, err := sl.base.Allow(ctx, rdb, cb, log, scope, identifier, cost)
+	if err != nil {
+		// a policy that is not enforced yet must never fail the request
+		log.Warn().Err(err).Str("policy", sl.policy).Msg("Shadow policy could not be evaluated")
+		if response == nil {
+			response = &models.LimiterResponse{}
+		}
+		response.Allowed = true
+		response.Degraded = true
+		response.Reason = FailureReason(err)
+		return response, nil
+	}
+
+	if !response.Allowed {
+		metrics.ShadowDenials.WithLabelValues(sl.policy, sl.algo).Inc()
+		log.Info().Str("policy", sl.policy).Str("scope", scope).Str("identifier", identifier).Int64("cost", cost).Str("rule", response.Rule).Msg("Shadow policy would have rejected the request")
+
+		response.Allowed = true
+		response.RetryAfter = 0
+		response.ShadowDenied = true
+	}
+
+	return response, nil
+}
+
+func (sl *shadowLimiter) Release(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier, leaseId string) (bool, error) {
+	return ReleaseLease(ctx, sl.base, rdb, cb, log, scope, identifier, leaseId)
+}
+
+// Refund skips the requests the policy would have rejected or could not evaluate, they were never charged
+func (sl *shadowLimiter) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	if admitted.ShadowDenied || admitted.Degraded {
+		return nil
+	}
+	return RefundCost(ctx, sl.base, rdb, cb, log, scope, identifier, cost, admitted)
+}
+
+func (sl *shadowLimiter) Close() {
+	closeLimiter(sl.base)
+}
+
+// withMode runs the limiter in dry-run when the policy is in shadow mode
+func withMode(limiter RateLimiter, policy *models.PolicySchema) RateLimiter {
+	if policy.Mode != constants.ModeShadow {
+		return limiter
+	}
+	return newShadowLimiter(limiter, policy.Key(), policy.EffectiveAlgorithm())
+}
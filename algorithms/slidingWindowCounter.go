@@ -5,8 +5,6 @@ import (
 	"goapp/constants"
 	"goapp/lua"
 	"goapp/models"
-	"goapp/services"
-	"goapp/utils"
 	"sync"
 	"time"
 
@@ -37,41 +35,53 @@ func NewSlidingWindowCounter(windowStr string, capacity int, log zerolog.Logger)
 	}
 }
 
-func (sc *SlidingWindowCounterRedis) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, scope, identifier string) (*models.LimiterResponse, error) {
+func (sc *SlidingWindowCounterRedis) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
 	now := time.Now().UnixMilli()
 
 	windowMs := sc.window.Milliseconds()
 	// read data from the redis
-	redisKey := utils.StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingWindow, scope, identifier)
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingWindow, scope, identifier)
 
 	swcScript := redis.NewScript(lua.GetSlidingWindowScript())
 
 	results, err := cb.Cb.Execute(func() (any, error) {
-		results, err := swcScript.Run(ctx, rdb, []string{redisKey}, sc.capacity, windowMs, now, 1).Result()
+		results, err := swcScript.Run(ctx, rdb, []string{redisKey}, sc.capacity, windowMs, now, cost).Result()
 
 		return results, err
 	})
 
-	allowed := results.([]any)[0].(bool)
-	tokens := results.([]any)[1].(int64)
-
-	retryAfter := now + (windowMs - tokens)
-
 	if err != nil {
 		log.Error().Err(err).Msg("Error calling the sliding window counter script, rejecting the request")
 		return &models.LimiterResponse{
-			Allowed:       false,
-			RetryAfter:    int64(retryAfter),
-			RemainingTokens: int64(tokens),
-			TotalTokens : int64(sc.capacity),
+			Allowed:         false,
+			RetryAfter:      0,
+			RemainingTokens: 0,
+			TotalTokens:     int64(sc.capacity),
 		}, err
-	} else {
-		log.Info().Msg("Accepting the request")
 	}
+
+	values, err := parseScriptValues(results, 4)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the sliding window counter script result")
+		return nil, err
+	}
+
+	allowed := values[0] == 1
+	if !allowed {
+		log.Warn().Str("scope", scope).Msg("Request is rejected, threshold exceeded")
+	}
+
 	return &models.LimiterResponse{
-		Allowed:       allowed,
-		RetryAfter:    int64(retryAfter),
-		RemainingTokens: int64(tokens),
-		TotalTokens : int64(sc.capacity),
+		Allowed:         allowed,
+		RetryAfter:      secondsUntil(time.Duration(values[2]) * time.Millisecond),
+		RemainingTokens: values[1],
+		TotalTokens:     int64(sc.capacity),
+		Reset:           secondsUntil(time.Duration(values[3]) * time.Millisecond),
+		Window:          secondsUntil(sc.window),
 	}, nil
 }
+
+func (sc *SlidingWindowCounterRedis) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingWindow, scope, identifier)
+	return runRefundScript(ctx, rdb, cb, lua.GetSlidingWindowRefundScript(), []string{redisKey}, time.Now().UnixMilli(), cost, sc.capacity, sc.window.Milliseconds())
+}
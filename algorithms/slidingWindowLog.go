@@ -2,57 +2,81 @@ package algorithms
 
 import (
 	"context"
-	"fmt"
-	"goapp/services"
-	"sync"
+	"goapp/constants"
+	"goapp/lua"
+	"goapp/models"
+	"math/rand/v2"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 )
 
-type SlidingWindowLog struct {
+type SlidingWindowLogRedis struct {
 	window   time.Duration
 	capacity int64
-	logs     []time.Time
-	mu       sync.Mutex
 }
 
-func GetNewSlidingWindowLog(window time.Duration, capacity int64) *SlidingWindowLog {
-	return &SlidingWindowLog{
+func NewSlidingWindowLog(windowStr string, capacity int64, log zerolog.Logger) *SlidingWindowLogRedis {
+	window, err := time.ParseDuration(windowStr)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the duration")
+	}
+
+	return &SlidingWindowLogRedis{
 		window:   window,
 		capacity: capacity,
-		logs:     make([]time.Time, 0),
 	}
 }
 
-func (sl *SlidingWindowLog) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, key string) (bool, error) {
-	sl.mu.Lock()
-	defer sl.mu.Unlock()
+func (sl *SlidingWindowLogRedis) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
+	now := time.Now().UnixMilli()
+
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingLog, scope, identifier)
+
+	// every logged request needs a unique member in the sorted set, even when the timestamps collide
+	member := strconv.FormatInt(now, 10) + ":" + strconv.FormatUint(rand.Uint64(), 36)
 
-	now := time.Now()
-	threshold := now.Add(-sl.window)
+	slScript := redis.NewScript(lua.GetSlidingWindowLogScript())
 
-	// 1. Drop timestamps older than the window
-	dropCnt := 0
-	for _, t := range sl.logs {
-		if t.After(threshold) {
-			break
-		}
-		dropCnt++
+	results, err := cb.Cb.Execute(func() (any, error) {
+		results, err := slScript.Run(ctx, rdb, []string{redisKey}, sl.capacity, sl.window.Milliseconds(), now, cost, member).Result()
+		return results, err
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg("Error calling the sliding window log script, rejecting the request")
+		return &models.LimiterResponse{
+			Allowed:         false,
+			RetryAfter:      0,
+			RemainingTokens: 0,
+			TotalTokens:     sl.capacity,
+		}, err
 	}
 
-	// 2. Remove the old timestamps
-	if dropCnt > 0 {
-		sl.logs = sl.logs[dropCnt:]
+	values, err := parseScriptValues(results, 4)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the sliding window log script result")
+		return nil, err
 	}
 
-	// 3. Accept the request if the current log count is less than capacity
-	if int64(len(sl.logs)) < sl.capacity {
-		sl.logs = append(sl.logs, now)
-		fmt.Println("Request is accepted") // You might want to remove print statements in production!
-		return true, nil
+	allowed := values[0] == 1
+	if !allowed {
+		log.Warn().Str("scope", scope).Msg("Request is rejected, window is full")
 	}
-	fmt.Println("Request is rejected")
-	return false, nil
+
+	return &models.LimiterResponse{
+		Allowed:         allowed,
+		RetryAfter:      secondsUntil(time.Duration(values[2]) * time.Millisecond),
+		RemainingTokens: values[1],
+		TotalTokens:     sl.capacity,
+		Reset:           secondsUntil(time.Duration(values[3]) * time.Millisecond),
+		Window:          secondsUntil(sl.window),
+	}, nil
+}
+
+func (sl *SlidingWindowLogRedis) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmSlidingLog, scope, identifier)
+	return runRefundScript(ctx, rdb, cb, lua.GetSlidingWindowLogRefundScript(), []string{redisKey}, cost)
 }
@@ -0,0 +1,27 @@
+package algorithms
+
+import (
+	"math"
+	"time"
+)
+
+// secondsUntil rounds a wait up to whole seconds so clients never retry too early
+func secondsUntil(wait time.Duration) int64 {
+	if wait <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(wait.Seconds()))
+}
+
+// bucketWait is the time a bucket needs to fill or drain amount at rate per second
+func bucketWait(amount, rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(amount / rate * float64(time.Second))
+}
+
+// bucketWindow is the period a bucket limit applies to, the time to fill or drain the whole capacity
+func bucketWindow(capacity, rate float64) int64 {
+	return secondsUntil(bucketWait(capacity, rate))
+}
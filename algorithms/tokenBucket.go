@@ -5,8 +5,6 @@ import (
 	"goapp/constants"
 	"goapp/lua"
 	"goapp/models"
-	"goapp/services"
-	"goapp/utils"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -30,39 +28,50 @@ func NewTokenBucket(maxTokens, refillRate float64, log zerolog.Logger) *TokenBuc
 	}
 }
 
-func (tb *TokenBucketRedis) Allow(ctx context.Context, rdb *redis.Client, cb *services.CircuitBreaker, log zerolog.Logger, scope, identifier string) (*models.LimiterResponse, error) {
+func (tb *TokenBucketRedis) Allow(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64) (*models.LimiterResponse, error) {
 	// get the information from the redis for the key
-	redisKey := utils.StringBuilder(constants.KeyRateLimit, constants.AlgorithmTokenBucket, scope, identifier)
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmTokenBucket, scope, identifier)
 
 	tokenBucketScript := redis.NewScript(lua.GetTokenBucketScript())
 	now := float64(time.Now().UnixNano()) / 1e9
 
 	results, err := cb.Cb.Execute(func() (any, error) {
-		results, err := tokenBucketScript.Run(ctx, rdb, []string{redisKey}, tb.MaxTokens, tb.RefillRate, now, 1).Result()
+		results, err := tokenBucketScript.Run(ctx, rdb, []string{redisKey}, tb.MaxTokens, tb.RefillRate, now, cost).Result()
 		return results, err
 	})
 
-	allowed := results.([]any)[0].(bool)
-	currentTokens := results.([]any)[1].(int64)
-
 	if err != nil {
 		log.Error().Err(err).Msg("Error running the token bucket script")
 		return &models.LimiterResponse{
-			Allowed:       false,
-			RetryAfter:    0,
+			Allowed:         false,
+			RetryAfter:      0,
 			RemainingTokens: 0,
-			TotalTokens : int64(tb.MaxTokens),
+			TotalTokens:     int64(tb.MaxTokens),
 		}, err
-	} else {
-		log.Info().Msg("Accepting the request")
 	}
 
-	retryAfter := now + (tb.MaxTokens-float64(currentTokens))/tb.RefillRate
+	values, err := parseScriptValues(results, 4)
+	if err != nil {
+		log.Error().Err(err).Msg("Error parsing the token bucket script result")
+		return nil, err
+	}
+
+	allowed := values[0] == 1
+	if !allowed {
+		log.Warn().Str("scope", scope).Msg("Request is getting rejected, bucket is empty")
+	}
 
 	return &models.LimiterResponse{
-		Allowed:       allowed,
-		RetryAfter:    int64(retryAfter),
-		RemainingTokens: currentTokens,
-		TotalTokens : int64(tb.MaxTokens),
+		Allowed:         allowed,
+		RetryAfter:      secondsUntil(time.Duration(values[2]) * time.Millisecond),
+		RemainingTokens: values[1],
+		TotalTokens:     int64(tb.MaxTokens),
+		Reset:           secondsUntil(time.Duration(values[3]) * time.Millisecond),
+		Window:          bucketWindow(tb.MaxTokens, tb.RefillRate),
 	}, nil
 }
+
+func (tb *TokenBucketRedis) Refund(ctx context.Context, rdb redis.Scripter, cb *CircuitBreaker, log zerolog.Logger, scope, identifier string, cost int64, admitted *models.LimiterResponse) error {
+	redisKey := StringBuilder(constants.KeyRateLimit, constants.AlgorithmTokenBucket, scope, identifier)
+	return runRefundScript(ctx, rdb, cb, lua.GetTokenBucketRefundScript(), []string{redisKey}, tb.MaxTokens, cost)
+}
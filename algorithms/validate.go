@@ -0,0 +1,120 @@
+package algorithms
+
+import (
+	"errors"
+	"fmt"
+	"goapp/constants"
+	"goapp/models"
+	"strings"
+	"time"
+)
+
+// windowed lists the algorithms that cannot work without a window
+var windowed = map[string]bool{
+	constants.AlgorithmFixedWindow:   true,
+	constants.AlgorithmSlidingWindow: true,
+	constants.AlgorithmSlidingLog:    true,
+	constants.AlgorithmGCRA:          true,
+}
+
+// ValidatePolicy checks that a policy can be turned into a limiter before it is stored
+func ValidatePolicy(policy *models.PolicySchema) error {
+	if policy.Scope == "" || policy.Identifier == "" {
+		return errors.New("scope and identifier are required")
+	}
+	if strings.Contains(policy.Scope, ":") {
+		return errors.New("scope cannot contain ':'")
+	}
+	if key := policy.Scope + ":" + policy.Identifier; len(key) > constants.PolicyKeyLength {
+		return fmt.Errorf("policy key %s is longer than %d characters", key, constants.PolicyKeyLength)
+	}
+
+	switch policy.FailureMode {
+	case "", constants.FailureModeOpen, constants.FailureModeClosed, constants.FailureModeFallback:
+	default:
+		return fmt.Errorf("unknown failure mode: %s", policy.FailureMode)
+	}
+
+	switch policy.Mode {
+	case "", constants.ModeEnforce, constants.ModeShadow:
+	default:
+		return fmt.Errorf("unknown mode: %s", policy.Mode)
+	}
+
+	// every schedule has to leave a valid policy behind while it is active
+	for i := range policy.Schedules {
+		schedule := &policy.Schedules[i]
+		if err := schedule.Validate(); err != nil {
+			return err
+		}
+
+		if err := validateLimits(policy.WithSchedule(schedule)); err != nil {
+			return fmt.Errorf("schedule %s : %w", schedule.Name, err)
+		}
+	}
+
+	return validateLimits(policy)
+}
+
+// validateLimits checks the limits of the policy, leaving its key and modes aside
+func validateLimits(policy *models.PolicySchema) error {
+	if len(policy.Rules) > 0 {
+		return validateRules(policy.Rules)
+	}
+
+	if _, ok := registry[policy.Algorithm]; !ok || policy.Algorithm == constants.AlgorithmMultiWindow {
+		return fmt.Errorf("unknown algorithm: %s", policy.Algorithm)
+	}
+
+	if policy.Limit <= 0 {
+		return errors.New("limit must be positive")
+	}
+	if policy.Burst < 0 {
+		return errors.New("burst cannot be negative")
+	}
+
+	switch policy.Algorithm {
+	case constants.AlgorithmTokenBucket, constants.AlgorithmLeakyBucket:
+		// the burst is the refill or leak rate of the bucket
+		if policy.Burst == 0 {
+			return fmt.Errorf("burst must be positive for %s", policy.Algorithm)
+		}
+	}
+
+	if policy.Window == "" {
+		if windowed[policy.Algorithm] {
+			return fmt.Errorf("window is required for %s", policy.Algorithm)
+		}
+		return nil
+	}
+
+	return validateWindow(policy.Window)
+}
+
+func validateRules(rules []models.LimitRule) error {
+	names := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		if rule.Limit <= 0 {
+			return fmt.Errorf("limit of rule %d must be positive", i)
+		}
+		if err := validateWindow(rule.Window); err != nil {
+			return fmt.Errorf("rule %d : %w", i, err)
+		}
+		if rule.Name != "" && names[rule.Name] {
+			return fmt.Errorf("rule name %s is used more than once", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+func validateWindow(windowStr string) error {
+	window, err := time.ParseDuration(windowStr)
+	if err != nil {
+		return fmt.Errorf("invalid window %q : %w", windowStr, err)
+	}
+	if window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"goapp/constants"
+	"goapp/logic"
+	"goapp/models"
+	"goapp/store"
+	"goapp/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	CommandPolicies = "policies"
+
+	formatYAML = "yaml"
+	formatJSON = "json"
+)
+
+const policiesUsage = `usage: rateLimiter policies <command> [flags]
+
+commands:
+  export    write every stored policy to a file (or stdout)
+  validate  check a policy file without touching the database
+  diff      show what apply would change
+  apply     make the stored policies match the file in a single transaction
+`
+
+// RunPolicies runs the policies subcommand and returns the exit code
+func RunPolicies(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, policiesUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet(CommandPolicies+" "+args[0], flag.ContinueOnError)
+	configPath := flags.String("config", "deploy/config.json", "path of the service config")
+	file := flags.String("file", "", "policy file, yaml or json by extension")
+	format := flags.String("format", "", "export format, yaml or json (defaults to the file extension, then yaml)")
+	prune := flags.Bool("prune", false, "also delete stored policies missing from the file, check them with diff -prune first")
+	actor := flags.String("actor", "cli", "name the changes are recorded under in the policy history")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	log := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
+
+	// validation works offline, everything else needs the database
+	if args[0] == "validate" {
+		return validatePolicies(*file)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.CommandTimeout)
+	defer cancel()
+
+	config := &utils.Config{}
+	if err := config.LoadConfig(*configPath); err != nil {
+		log.Error().Err(err).Msg("Error loading the config file")
+		return 1
+	}
+
+	db, err := config.Database.InitDb(ctx, log, config.Queries)
+	if err != nil {
+		return 1
+	}
+	defer db.Db.Close()
+
+	// the cli only writes, nobody listens for the changes it makes
+	policyStore := store.NewPostgresPolicyStore(db, "")
+
+	switch args[0] {
+	case "export":
+		return exportPolicies(ctx, policyStore, log, *file, *format)
+	case "diff", "apply":
+		policies, err := readPolicyFile(*file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		var changes []*store.PolicyChange
+		if args[0] == "diff" {
+			changes, err = logic.PlanPolicySync(ctx, policyStore, log, policies, *prune)
+		} else {
+			changes, err = logic.ApplyPolicySync(ctx, policyStore, log, policies, *prune, *actor)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		printChanges(os.Stdout, changes)
+		return 0
+	}
+
+	fmt.Fprint(os.Stderr, policiesUsage)
+	return 2
+}
+
+func validatePolicies(file string) int {
+	policies, err := readPolicyFile(file)
+	if err == nil {
+		err = logic.ValidatePolicies(policies)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("%d policies are valid\n", len(policies))
+	return 0
+}
+
+func readPolicyFile(file string) ([]*models.PolicySchema, error) {
+	if file == "" {
+		return nil, errors.New("a policy file is required, pass it with -file")
+	}
+	return store.ReadPolicyFile(file)
+}
+
+func exportPolicies(ctx context.Context, policyStore store.VersionedPolicyRepository, log zerolog.Logger, file, format string) int {
+	records, err := logic.ListPolicies(ctx, policyStore, log, "")
+	if err != nil {
+		return 1
+	}
+
+	policyFile := models.PolicyFile{Policies: make([]*models.PolicySchema, 0, len(records))}
+	for _, record := range records {
+		// versions belong to the database, the file only describes the limits
+		policy := record.PolicySchema
+		policy.Version = 0
+		policyFile.Policies = append(policyFile.Policies, &policy)
+	}
+	sort.Slice(policyFile.Policies, func(i, j int) bool {
+		return policyFile.Policies[i].Key() < policyFile.Policies[j].Key()
+	})
+
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(file), ".")
+		if format == "yml" || format == "" {
+			format = formatYAML
+		}
+	}
+
+	var content []byte
+	switch format {
+	case formatYAML:
+		content, err = yaml.Marshal(policyFile)
+	case formatJSON:
+		content, err = sonic.ConfigStd.MarshalIndent(policyFile, "", "  ")
+	default:
+		err = fmt.Errorf("unknown format %s, use yaml or json", format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if file == "" {
+		os.Stdout.Write(content)
+		return 0
+	}
+	if err := os.WriteFile(file, content, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d policies to %s\n", len(policyFile.Policies), file)
+	return 0
+}
+
+var changeSymbols = map[string]string{
+	constants.PolicyActionCreate: "+",
+	constants.PolicyActionUpdate: "~",
+	constants.PolicyActionDelete: "-",
+}
+
+func printChanges(w io.Writer, changes []*store.PolicyChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "policies are in sync, nothing to change")
+		return
+	}
+
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s %s\n", changeSymbols[change.Action], change.Key)
+		if change.Action != constants.PolicyActionUpdate {
+			continue
+		}
+
+		fields := make([]string, 0, len(change.Diff))
+		for field := range change.Diff {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			fmt.Fprintf(w, "    %s: %s -> %s\n", field, fieldValue(change.Diff[field].From), fieldValue(change.Diff[field].To))
+		}
+	}
+}
+
+func fieldValue(value any) string {
+	if value == nil {
+		return "(none)"
+	}
+	raw, err := sonic.MarshalString(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return raw
+}
@@ -11,6 +11,10 @@ const (
 	KeyUserId        = "userId"
 	KeyScope         = "scope"
 	KeyIdentifier    = "identifier"
+	KeyCost          = "cost"
+	KeyLeaseId       = "leaseId"
+	KeyUpdatedAt     = "updatedAt"
+	KeyVersion       = "version"
 
 	// Values
 	ValeTypeMemory = "memory"
@@ -21,14 +25,104 @@ const (
 	AlgorithmLeakyBucket   = "leaky_bucket"
 	AlgorithmFixedWindow   = "fixed_window"
 	AlgorithmSlidingWindow = "sliding_window"
+	AlgorithmSlidingLog    = "sliding_log"
+	AlgorithmGCRA          = "gcra"
+	AlgorithmConcurrency   = "concurrency"
+	AlgorithmMultiWindow   = "multi_window"
+
+	// Hierarchy levels, from the broadest to the most specific
+	LevelGlobal      = "global"
+	LevelTenant      = "tenant"
+	LevelUser        = "user"
+	LevelRoute       = "route"
+	GlobalIdentifier = "default"
+
+	// Failure modes
+	FailureModeOpen     = "open"
+	FailureModeClosed   = "closed"
+	FailureModeFallback = "fallback"
+
+	// Policy modes, shadow policies are evaluated but never reject a request
+	ModeEnforce = "enforce"
+	ModeShadow  = "shadow"
+
+	// Degraded decision reasons
+	ReasonCircuitOpen        = "circuit_open"
+	ReasonBackendUnavailable = "backend_unavailable"
+	ReasonNoPolicy           = "no_policy"
+
+	// Policy history actions
+	PolicyActionCreate   = "create"
+	PolicyActionUpdate   = "update"
+	PolicyActionDelete   = "delete"
+	PolicyActionRollback = "rollback"
+
+	// Policy changes made without naming an actor are recorded under this one
+	HeaderActor  = "X-Actor"
+	DefaultActor = "api"
+
+	// Writes made through the policy repository itself, which takes no actor
+	RepositoryActor = "repository"
+
+	// Requests consume a single unit unless a cost is given
+	DefaultCost = 1
+
+	// Upper bound on the number of items of a batch request
+	MaxBatchItems = 100
+
+	// Policy keys are stored in a column of this size
+	PolicyKeyLength = 50
+
+	// Sources the forward-auth rules take the identifier from
+	ForwardAuthSourceHeader = "header"
+	ForwardAuthSourcePath   = "path"
+	ForwardAuthSourceIp     = "ip"
+
+	// Headers proxies describe the original request with, nginx sends the X-Original-URI it is configured to
+	HeaderForwardedUri = "X-Forwarded-Uri"
+	HeaderOriginalUri  = "X-Original-URI"
+	HeaderForwardedFor = "X-Forwarded-For"
+
+	// Header sets the limiter responses carry, the legacy X-RateLimit-* ones, the IETF draft RateLimit and
+	// RateLimit-Policy ones or both of them
+	RateLimitHeadersLegacy = "legacy"
+	RateLimitHeadersIetf   = "ietf"
+	RateLimitHeadersBoth   = "both"
+
+	// Envoy asks for global limits, so its descriptors are always checked against redis
+	EnvoyLimiterType = ValueTypeRedis
+
+	// Wait retries this often when a rejection does not tell how long to wait
+	WaitRetryInterval = 1 * time.Second
 
 	// Timeouts
 	ContextTimeout               = 5 * time.Second
 	RequestTimeout               = 2 * time.Second
+	CommandTimeout               = 1 * time.Minute
 	CircuitBreakerInterval       = 10 * time.Second
 	CircuitBreakerTimeout        = 30 * time.Second
 	ConsecutiveFailuresThreshold = 5
 
+	// Leases of the concurrency limiter expire after this when the policy has no window
+	DefaultLeaseTTL = 30 * time.Second
+
 	// Cache details
 	PolicyCacheDuration = 5 * time.Minute
+
+	// Keys without any policy are remembered for a shorter time so that newly added policies apply quickly
+	MissingPolicyCacheDuration = 30 * time.Second
+
+	// Policy stores, a file store checks its file for changes this often unless configured
+	PolicyStorePostgres       = "postgres"
+	PolicyStoreFile           = "file"
+	DefaultPolicyPollInterval = 1 * time.Second
+
+	// Reconnecting to the policy change notifications backs off between these
+	PolicyListenRetryInterval    = 1 * time.Second
+	PolicyListenMaxRetryInterval = 30 * time.Second
+
+	// Limiter instances
+	LimiterIdleTimeout     = 30 * time.Minute
+	LimiterJanitorInterval = 1 * time.Minute
+	EvictionSampleSize     = 16
 )
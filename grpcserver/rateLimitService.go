@@ -0,0 +1,205 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goapp/constants"
+	"goapp/logic"
+	"goapp/models"
+	"goapp/ratelimit"
+	"strings"
+	"time"
+
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// the entry keys of a descriptor are joined into the scope and its entry values into the identifier, identifiers too
+// long for a policy key are replaced by the prefix and the first hex digits of their sha-256
+const (
+	descriptorKeySeparator   = "."
+	descriptorValueSeparator = "|"
+	policyKeySeparator       = ":"
+
+	hashedIdentifierPrefix = "#"
+	hashedIdentifierDigits = 16
+)
+
+// windowUnits are the windows envoy can report a limit in, other windows are reported without a unit
+var windowUnits = map[time.Duration]rlsv3.RateLimitResponse_RateLimit_Unit{
+	time.Second:        rlsv3.RateLimitResponse_RateLimit_SECOND,
+	time.Minute:        rlsv3.RateLimitResponse_RateLimit_MINUTE,
+	time.Hour:          rlsv3.RateLimitResponse_RateLimit_HOUR,
+	24 * time.Hour:     rlsv3.RateLimitResponse_RateLimit_DAY,
+	7 * 24 * time.Hour: rlsv3.RateLimitResponse_RateLimit_WEEK,
+}
+
+// rateLimitService implements the envoy rate limit service on top of the batch checks, so that envoy gets the same
+// algorithms and redis scripts as every other caller
+type rateLimitService struct {
+	rlsv3.UnimplementedRateLimitServiceServer
+
+	server *Server
+}
+
+// descriptorKey maps a descriptor onto a policy, the domain and the entry keys make up the scope and the entry
+// values the identifier, so remote_address=10.0.0.1 in the edge domain is checked against edge.remote_address:10.0.0.1.
+// The scope ends at the first colon of a policy key, so the domain and the entry keys cannot contain one
+func descriptorKey(domain string, descriptor *ratelimitv3.RateLimitDescriptor) (string, string, error) {
+	if strings.Contains(domain, policyKeySeparator) {
+		return "", "", fmt.Errorf("domain %s cannot contain %q", domain, policyKeySeparator)
+	}
+
+	keys := []string{domain}
+	values := make([]string, 0, len(descriptor.Entries))
+	for _, entry := range descriptor.Entries {
+		if strings.Contains(entry.Key, policyKeySeparator) {
+			return "", "", fmt.Errorf("entry key %s cannot contain %q", entry.Key, policyKeySeparator)
+		}
+		keys = append(keys, entry.Key)
+		values = append(values, entry.Value)
+	}
+	return strings.Join(keys, descriptorKeySeparator), strings.Join(values, descriptorValueSeparator), nil
+}
+
+// fitPolicyKey hashes an identifier that would make the key longer than a policy key, so that the key still fits and
+// an exact policy can be written for it. False when the scope leaves no room even for the hashed identifier
+func fitPolicyKey(scope, identifier string) (string, bool) {
+	if len(scope)+len(policyKeySeparator)+len(identifier) <= constants.PolicyKeyLength {
+		return identifier, true
+	}
+
+	sum := sha256.Sum256([]byte(identifier))
+	hashed := hashedIdentifierPrefix + hex.EncodeToString(sum[:])[:hashedIdentifierDigits]
+	return hashed, len(scope)+len(policyKeySeparator)+len(hashed) <= constants.PolicyKeyLength
+}
+
+func (rs *rateLimitService) ShouldRateLimit(ctx context.Context, request *rlsv3.RateLimitRequest) (*rlsv3.RateLimitResponse, error) {
+	if request.Domain == "" || len(request.Descriptors) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "a domain and at least one descriptor are required")
+	}
+
+	log := rs.server.requestLogger(ctx).With().Str("domain", request.Domain).Logger()
+
+	// Create a timeout-bounded context for the request
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	// descriptors no policy applies to are not limited, as they are by envoy's own rate limit service
+	statuses := make([]*rlsv3.RateLimitResponse_DescriptorStatus, len(request.Descriptors))
+	policies := make([]*models.PolicySchema, len(request.Descriptors))
+	batch := &models.BatchRequest{}
+	checked := make([]int, 0, len(request.Descriptors))
+
+	for i, descriptor := range request.Descriptors {
+		if len(descriptor.Entries) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "descriptors need at least one entry")
+		}
+
+		statuses[i] = &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}
+
+		scope, identifier, err := descriptorKey(request.Domain, descriptor)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "descriptor %d : %s", i, err)
+		}
+		identifier, fits := fitPolicyKey(scope, identifier)
+		if !fits {
+			return nil, status.Errorf(codes.InvalidArgument, "descriptor %d : scope %s is too long for a policy key of at most %d characters", i, scope, constants.PolicyKeyLength)
+		}
+
+		policy, exists := rs.server.cache.GetPolicy(ctx, log, scope, identifier)
+		if !exists {
+			continue
+		}
+
+		cost := int64(request.HitsAddend)
+		if descriptor.HitsAddend != nil {
+			cost = int64(descriptor.HitsAddend.Value)
+		}
+
+		policies[i] = policy
+		checked = append(checked, i)
+		batch.Items = append(batch.Items, models.BatchItem{
+			Scope:      scope,
+			Identifier: identifier,
+			Type:       constants.EnvoyLimiterType,
+			Cost:       cost,
+		})
+	}
+
+	response := &rlsv3.RateLimitResponse{
+		OverallCode: rlsv3.RateLimitResponse_OK,
+		Statuses:    statuses,
+	}
+	if len(batch.Items) == 0 {
+		return response, nil
+	}
+
+	decisions, err := logic.CheckBatch(ctx, rs.server.rdb, log, rs.server.limiters, rs.server.cache, batch)
+	if errors.Is(err, logic.ErrInvalidBatch) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, errCheckFailure
+	}
+
+	for n, result := range decisions.Results {
+		// envoy applies its failure_mode_deny setting when the service fails
+		if result.Decision == nil {
+			return nil, errCheckFailure
+		}
+
+		i := checked[n]
+		statuses[i] = descriptorStatus(policies[i], result.Decision)
+		if statuses[i].Code == rlsv3.RateLimitResponse_OVER_LIMIT {
+			response.OverallCode = rlsv3.RateLimitResponse_OVER_LIMIT
+		}
+	}
+
+	return response, nil
+}
+
+func descriptorStatus(policy *models.PolicySchema, allowed *ratelimit.Decision) *rlsv3.RateLimitResponse_DescriptorStatus {
+	descriptorStatus := &rlsv3.RateLimitResponse_DescriptorStatus{
+		Code:           rlsv3.RateLimitResponse_OK,
+		LimitRemaining: uint32(max(allowed.Remaining, 0)),
+		CurrentLimit: &rlsv3.RateLimitResponse_RateLimit{
+			Name:            policy.Key(),
+			RequestsPerUnit: uint32(max(allowed.Limit, 0)),
+			Unit:            limitUnit(policy, allowed.Rule),
+		},
+	}
+
+	// envoy turns the duration into its reset header, a rejected client is told when it may retry
+	untilReset := allowed.ResetAfter
+	if !allowed.Allowed {
+		descriptorStatus.Code = rlsv3.RateLimitResponse_OVER_LIMIT
+		untilReset = allowed.RetryAfter
+	}
+	if untilReset > 0 {
+		descriptorStatus.DurationUntilReset = durationpb.New(untilReset)
+	}
+	return descriptorStatus
+}
+
+// limitUnit is the unit of the window that decided, the rule named in the response for multi-limit policies
+func limitUnit(policy *models.PolicySchema, ruleName string) rlsv3.RateLimitResponse_RateLimit_Unit {
+	window := policy.Window
+	for _, rule := range policy.Rules {
+		if rule.Name != "" && rule.Name == ruleName {
+			window = rule.Window
+		}
+	}
+
+	duration, err := time.ParseDuration(window)
+	if err != nil {
+		return rlsv3.RateLimitResponse_RateLimit_UNKNOWN
+	}
+	return windowUnits[duration]
+}
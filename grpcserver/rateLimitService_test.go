@@ -0,0 +1,132 @@
+package grpcserver_test
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/grpcserver"
+	"goapp/logic"
+	"goapp/models"
+	"goapp/ratelimit"
+	"goapp/services"
+	"goapp/store"
+	"net"
+	"testing"
+
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newRateLimitClient serves the envoy rate limit service over an in-memory connection, with the envoy limiter type
+// backed by memory
+func newRateLimitClient(t *testing.T, policies ...*models.PolicySchema) rlsv3.RateLimitServiceClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cache := services.NewCache(store.NewMemoryPolicyStore(policies...))
+	cache.LoadCache(ctx, zerolog.Nop())
+
+	backends := ratelimit.NewBackends(ctx, ratelimit.Options{})
+	limiters := logic.Limiters{constants.EnvoyLimiterType: ratelimit.New(backends.Memory(), cache)}
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpcserver.NewServer(zerolog.Nop(), nil, limiters, cache)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return rlsv3.NewRateLimitServiceClient(conn)
+}
+
+func descriptor(entries ...string) *ratelimitv3.RateLimitDescriptor {
+	descriptor := &ratelimitv3.RateLimitDescriptor{}
+	for i := 0; i < len(entries); i += 2 {
+		descriptor.Entries = append(descriptor.Entries, &ratelimitv3.RateLimitDescriptor_Entry{Key: entries[i], Value: entries[i+1]})
+	}
+	return descriptor
+}
+
+func TestShouldRateLimitDescriptorKeys(t *testing.T) {
+	client := newRateLimitClient(t,
+		&models.PolicySchema{Scope: "edge.remote_address", Identifier: "*", Limit: 1, Window: "1m", Algorithm: constants.AlgorithmFixedWindow},
+	)
+
+	tests := []struct {
+		name       string
+		domain     string
+		descriptor *ratelimitv3.RateLimitDescriptor
+		code       codes.Code
+		overall    rlsv3.RateLimitResponse_Code
+	}{
+		{
+			name:       "descriptor within its limit",
+			domain:     "edge",
+			descriptor: descriptor("remote_address", "10.0.0.1"),
+			code:       codes.OK,
+			overall:    rlsv3.RateLimitResponse_OK,
+		},
+		{
+			name:       "descriptor over its limit",
+			domain:     "edge",
+			descriptor: descriptor("remote_address", "10.0.0.1"),
+			code:       codes.OK,
+			overall:    rlsv3.RateLimitResponse_OVER_LIMIT,
+		},
+		{
+			name:       "colon in an entry value is part of the identifier",
+			domain:     "edge",
+			descriptor: descriptor("remote_address", "::1"),
+			code:       codes.OK,
+			overall:    rlsv3.RateLimitResponse_OK,
+		},
+		{
+			name:       "colon in the domain",
+			domain:     "edge:v2",
+			descriptor: descriptor("remote_address", "10.0.0.1"),
+			code:       codes.InvalidArgument,
+		},
+		{
+			name:       "colon in an entry key",
+			domain:     "edge",
+			descriptor: descriptor("remote:address", "10.0.0.1"),
+			code:       codes.InvalidArgument,
+		},
+		{
+			name:       "descriptor without entries",
+			domain:     "edge",
+			descriptor: descriptor(),
+			code:       codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := client.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{
+				Domain:      tt.domain,
+				Descriptors: []*ratelimitv3.RateLimitDescriptor{tt.descriptor},
+			})
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("expected %s, got %s : %v", tt.code, code, err)
+			}
+			if err != nil {
+				return
+			}
+			if response.OverallCode != tt.overall {
+				t.Fatalf("expected %s, got %s", tt.overall, response.OverallCode)
+			}
+		})
+	}
+}
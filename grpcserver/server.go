@@ -0,0 +1,208 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"goapp/algorithms"
+	"goapp/constants"
+	"goapp/logic"
+	"goapp/models"
+	"goapp/proto/ratelimiterpb"
+	"goapp/ratelimit"
+	"goapp/services"
+	"io"
+	"time"
+
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errMissingKey   = status.Error(codes.InvalidArgument, "missing scope and identifier, or at least one of tenant, user and route")
+	errInvalidCost  = status.Error(codes.InvalidArgument, "cost must be a positive integer")
+	errCheckFailure = status.Error(codes.Internal, "internal server error while evaluating rate limits")
+)
+
+// Server answers the gRPC decision calls with the same limiters and policy cache as the http api
+type Server struct {
+	ratelimiterpb.UnimplementedRateLimiterServer
+
+	log      zerolog.Logger
+	rdb      *redis.Client
+	limiters logic.Limiters
+	cache    *services.Cache
+}
+
+func NewServer(log zerolog.Logger, rdb *redis.Client, limiters logic.Limiters, cache *services.Cache) *grpc.Server {
+	server := &Server{
+		log:      log,
+		rdb:      rdb,
+		limiters: limiters,
+		cache:    cache,
+	}
+
+	grpcServer := grpc.NewServer()
+	ratelimiterpb.RegisterRateLimiterServer(grpcServer, server)
+	// envoy calls the same server as a global rate limit service
+	rlsv3.RegisterRateLimitServiceServer(grpcServer, &rateLimitService{server: server})
+	return grpcServer
+}
+
+// requestLogger tags the logs with the request id the caller sent, like the http request logger does
+func (s *Server) requestLogger(ctx context.Context) zerolog.Logger {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestIds := md.Get("x-request-id")
+	if len(requestIds) == 0 {
+		return s.log
+	}
+	return s.log.With().Str("request_id", requestIds[0]).Logger()
+}
+
+func (s *Server) Check(ctx context.Context, request *ratelimiterpb.CheckRequest) (*ratelimiterpb.CheckResponse, error) {
+	return s.check(ctx, s.requestLogger(ctx), request)
+}
+
+func (s *Server) check(ctx context.Context, log zerolog.Logger, request *ratelimiterpb.CheckRequest) (*ratelimiterpb.CheckResponse, error) {
+	// requests without a scope are checked against every level of the hierarchy they name
+	var levels []algorithms.Level
+	if request.Scope == "" && request.Identifier == "" {
+		var err error
+		levels, err = algorithms.BuildLevels(map[string]string{
+			constants.LevelTenant: request.Tenant,
+			constants.LevelUser:   request.User,
+			constants.LevelRoute:  request.Route,
+		})
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	if (request.Scope == "" || request.Identifier == "") && len(levels) == 0 {
+		return nil, errMissingKey
+	}
+
+	cost := request.Cost
+	if cost < 0 {
+		return nil, errInvalidCost
+	}
+	if cost == 0 {
+		cost = constants.DefaultCost
+	}
+
+	// Create a timeout-bounded context for the request
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	var allowed *ratelimit.Decision
+	var err error
+	if len(levels) > 0 {
+		allowed, err = logic.GetHierarchicalLimiter(ctx, log, s.limiters, levels, request.Type, cost)
+	} else {
+		allowed, err = logic.GetLimiter(ctx, log, s.limiters, request.Scope, request.Identifier, request.Type, cost)
+	}
+	if err != nil {
+		return nil, errCheckFailure
+	}
+
+	return toCheckResponse(allowed), nil
+}
+
+func (s *Server) BatchCheck(ctx context.Context, request *ratelimiterpb.BatchCheckRequest) (*ratelimiterpb.BatchCheckResponse, error) {
+	batch := &models.BatchRequest{
+		Items:        make([]models.BatchItem, len(request.Items)),
+		AllOrNothing: request.AllOrNothing,
+	}
+	for i, item := range request.Items {
+		batch.Items[i] = models.BatchItem{
+			Scope:      item.Scope,
+			Identifier: item.Identifier,
+			Type:       item.Type,
+			Cost:       item.Cost,
+		}
+	}
+
+	// Create a timeout-bounded context for the request
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	response, err := logic.CheckBatch(ctx, s.rdb, s.requestLogger(ctx), s.limiters, s.cache, batch)
+	if errors.Is(err, logic.ErrInvalidBatch) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, errCheckFailure
+	}
+
+	results := make([]*ratelimiterpb.CheckResponse, len(response.Results))
+	for i, result := range response.Results {
+		results[i] = &ratelimiterpb.CheckResponse{Error: result.Error}
+		if result.Decision != nil {
+			results[i] = toCheckResponse(result.Decision)
+		}
+
+		results[i].Charged = result.Charged
+		results[i].Skipped = result.Skipped
+		if result.RejectedBy != nil {
+			rejectedBy := int32(*result.RejectedBy)
+			results[i].RejectedBy = &rejectedBy
+		}
+	}
+
+	return &ratelimiterpb.BatchCheckResponse{
+		Allowed: response.Allowed,
+		Results: results,
+	}, nil
+}
+
+// StreamCheck keeps the stream open when a single request fails, its response carries the error instead
+func (s *Server) StreamCheck(stream ratelimiterpb.RateLimiter_StreamCheckServer) error {
+	log := s.requestLogger(stream.Context())
+
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		response, err := s.check(stream.Context(), log, request)
+		if err != nil {
+			response = &ratelimiterpb.CheckResponse{Error: status.Convert(err).Message()}
+		}
+
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+func toCheckResponse(allowed *ratelimit.Decision) *ratelimiterpb.CheckResponse {
+	return &ratelimiterpb.CheckResponse{
+		Allowed:       allowed.Allowed,
+		RetryAfter:    seconds(allowed.RetryAfter),
+		Remaining:     allowed.Remaining,
+		Limit:         allowed.Limit,
+		ResetAfter:    seconds(allowed.ResetAfter),
+		Window:        seconds(allowed.Window),
+		Degraded:      allowed.Degraded,
+		Reason:        allowed.Reason,
+		LeaseId:       allowed.LeaseId,
+		Rule:          allowed.Rule,
+		Level:         allowed.Level,
+		PolicyVersion: allowed.PolicyVersion,
+		ShadowDenied:  allowed.ShadowDenied,
+		Schedule:      allowed.Schedule,
+	}
+}
+
+// seconds rounds up, a client told to retry after 0 seconds would retry right away
+func seconds(duration time.Duration) int64 {
+	return int64((duration + time.Second - 1) / time.Second)
+}
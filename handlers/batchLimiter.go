@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"goapp/constants"
+	"goapp/logger"
+	"goapp/logic"
+	"goapp/models"
+
+	"github.com/bytedance/sonic"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (cfg *ConfigHandler) BatchLimiter(c *fiber.Ctx) error {
+	var request models.BatchRequest
+	if err := sonic.Unmarshal(c.Body(), &request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid batch body",
+		})
+	}
+
+	reqLog := logger.GetRequestLogger(c, cfg.log)
+
+	// Create a timeout-bounded context for the request
+	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
+	defer cancel()
+
+	response, err := logic.CheckBatch(ctx, cfg.rdb, reqLog, cfg.limiters, cfg.cache, &request)
+	if errors.Is(err, logic.ErrInvalidBatch) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error while evaluating rate limits",
+		})
+	}
+
+	if response.Allowed {
+		return c.Status(fiber.StatusOK).JSON(batchResponse(response))
+	}
+
+	// rejected when a limit denied an item, otherwise some items could not be evaluated at all
+	for _, result := range response.Results {
+		if result.Decision != nil && !result.Decision.Allowed {
+			return c.Status(fiber.StatusTooManyRequests).JSON(batchResponse(response))
+		}
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(batchResponse(response))
+}
@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"context"
-	"goapp/algorithms"
+	"goapp/logic"
 	"goapp/services"
 	"goapp/store"
 	"goapp/utils"
@@ -12,25 +12,27 @@ import (
 )
 
 type ConfigHandler struct {
-	ctx     context.Context
-	config  *utils.Config
-	log     zerolog.Logger
-	db      *store.Db
-	rdb     *redis.Client
-	cache   *services.Cache
-	factory algorithms.LimiterFactory
-	cb      *services.CircuitBreaker
+	ctx      context.Context
+	config   *utils.Config
+	log      zerolog.Logger
+	policies store.VersionedPolicyRepository
+	rdb      *redis.Client
+	cache    *services.Cache
+	limiters logic.Limiters
+
+	forwardAuth []*logic.ForwardAuthRule
 }
 
-func NewConfigHandler(ctx context.Context, config *utils.Config, log zerolog.Logger, db *store.Db, rdb *redis.Client, factory algorithms.LimiterFactory, cache *services.Cache, cb *services.CircuitBreaker) *ConfigHandler {
+func NewConfigHandler(ctx context.Context, config *utils.Config, log zerolog.Logger, policies store.VersionedPolicyRepository, rdb *redis.Client, limiters logic.Limiters, cache *services.Cache, forwardAuth []*logic.ForwardAuthRule) *ConfigHandler {
 	return &ConfigHandler{
-		ctx:     ctx,
-		config:  config,
-		log:     log,
-		db:      db,
-		rdb:     rdb,
-		cache:   cache,
-		factory: factory,
-		cb:      cb,
+		ctx:      ctx,
+		config:   config,
+		log:      log,
+		policies: policies,
+		rdb:      rdb,
+		cache:    cache,
+		limiters: limiters,
+
+		forwardAuth: forwardAuth,
 	}
 }
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/logger"
+	"goapp/logic"
+	"goapp/models"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// forwardedRequest reads the original request from the headers nginx auth_request and traefik ForwardAuth send
+func forwardedRequest(c *fiber.Ctx, forwardAuth models.ForwardAuth) logic.ForwardedRequest {
+	uri := c.Get(constants.HeaderForwardedUri)
+	if uri == "" {
+		uri = c.Get(constants.HeaderOriginalUri)
+	}
+	path := uri
+	if parsed, err := url.ParseRequestURI(uri); err == nil {
+		path = parsed.Path
+	}
+
+	return logic.ForwardedRequest{
+		Path: path,
+		Ip:   clientIp(c, forwardAuth.ClientIpHeader, forwardAuth.TrustedProxies),
+		Header: func(name string) string {
+			return c.Get(name)
+		},
+	}
+}
+
+// clientIp is the address the right-most untrusted proxy saw the request come from. Every proxy appends the address
+// it was called from to the header, the entries left of the ones added by the trusted proxies are sent by the client
+func clientIp(c *fiber.Ctx, header string, trustedProxies int) string {
+	if header == "" {
+		header = constants.HeaderForwardedFor
+	}
+
+	hops := strings.Split(c.Get(header), ",")
+	if i := len(hops) - 1 - trustedProxies; i >= 0 {
+		if ip := strings.TrimSpace(hops[i]); ip != "" {
+			return ip
+		}
+	}
+	return c.IP()
+}
+
+// ForwardAuth answers the auth subrequest of a proxy, 200 lets the original request through and 429 rejects it
+func (cfg *ConfigHandler) ForwardAuth(c *fiber.Ctx) error {
+	reqLog := logger.GetRequestLogger(c, cfg.log)
+
+	scope, identifier, found := logic.ForwardAuthKey(cfg.forwardAuth, forwardedRequest(c, cfg.config.ForwardAuth))
+	if !found {
+		// nothing to limit the request under, blocking it would take the proxied service down with a misconfiguration
+		reqLog.Debug().Msg("No forward-auth rule matched the request, letting it through")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	rateLimitType := cfg.config.ForwardAuth.Type
+	if rateLimitType == "" {
+		rateLimitType = constants.ValueTypeRedis
+	}
+
+	// Create a timeout-bounded context for the request
+	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
+	defer cancel()
+
+	decision, err := logic.GetLimiter(ctx, reqLog, cfg.limiters, scope, identifier, rateLimitType, constants.DefaultCost)
+	if err != nil {
+		decision = logic.ForwardAuthFailure(cfg.config.ForwardAuth.FailureMode, err)
+		reqLog.Warn().Err(err).Str("failureMode", cfg.config.ForwardAuth.FailureMode).Msg("Error evaluating the forward-auth request, applying the forward-auth failure mode")
+	}
+	allowed := limiterResponse(decision)
+
+	cfg.setRateLimitHeaders(c, allowed)
+
+	if !allowed.Allowed {
+		return c.Status(fiber.StatusTooManyRequests).JSON(allowed)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(allowed)
+}
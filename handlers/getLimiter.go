@@ -2,10 +2,11 @@ package handlers
 
 import (
 	"context"
+	"goapp/algorithms"
 	"goapp/constants"
 	"goapp/logic"
 	"goapp/logger"
-	"strconv"
+	"goapp/ratelimit"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -16,10 +17,28 @@ func (cfg *ConfigHandler) GetLimiter(c *fiber.Ctx) error {
 	identifier := queries[constants.KeyIdentifier]
 	rateLimitType := queries[constants.KeyRateLimitType]
 
+	// requests without a scope are checked against every level of the hierarchy they name
+	var levels []algorithms.Level
+	if scope == "" && identifier == "" {
+		var err error
+		if levels, err = algorithms.BuildLevels(queries); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
 	// validate the limiter type and algorithm
-	if scope == "" || identifier == "" {
+	if (scope == "" || identifier == "") && len(levels) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required query parameters: scope and identifier, or at least one of tenant, user and route",
+		})
+	}
+
+	cost, err := parseCost(c)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Missing required query parameters: scope and identifier",
+			"error": err.Error(),
 		})
 	}
 
@@ -29,18 +48,22 @@ func (cfg *ConfigHandler) GetLimiter(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
 	defer cancel()
 
-	allowed, err := logic.GetLimiter(ctx, cfg.db, cfg.rdb, cfg.config, reqLog, cfg.factory, cfg.cache, cfg.cb, scope, identifier, rateLimitType)
+	var decision *ratelimit.Decision
+	if len(levels) > 0 {
+		decision, err = logic.GetHierarchicalLimiter(ctx, reqLog, cfg.limiters, levels, rateLimitType, cost)
+	} else {
+		decision, err = logic.GetLimiter(ctx, reqLog, cfg.limiters, scope, identifier, rateLimitType, cost)
+	}
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Internal server error while evaluating rate limits",
 		})
 	}
+	allowed := limiterResponse(decision)
 
 	// adding to headers
-	c.Set("X-RateLimit-Limit", strconv.FormatInt(allowed.TotalTokens, 10))
-	c.Set("X-RateLimit-Remaining", strconv.FormatInt(allowed.RemainingTokens, 10))
-	c.Set("X-RateLimit-Retry-After", strconv.FormatInt(allowed.RetryAfter, 10))
+	cfg.setRateLimitHeaders(c, allowed)
 
 	if !allowed.Allowed {
 		return c.Status(fiber.StatusTooManyRequests).JSON(allowed)
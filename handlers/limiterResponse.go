@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"goapp/logic"
+	"goapp/models"
+	"goapp/ratelimit"
+	"time"
+)
+
+// limiterResponse is the decision as the api returns it, the durations in whole seconds
+func limiterResponse(decision *ratelimit.Decision) *models.LimiterResponse {
+	return &models.LimiterResponse{
+		Allowed:         decision.Allowed,
+		RetryAfter:      seconds(decision.RetryAfter),
+		RemainingTokens: decision.Remaining,
+		TotalTokens:     decision.Limit,
+		Reset:           seconds(decision.ResetAfter),
+		Window:          seconds(decision.Window),
+		Degraded:        decision.Degraded,
+		Reason:          decision.Reason,
+		LeaseId:         decision.LeaseId,
+		Rule:            decision.Rule,
+		Level:           decision.Level,
+		PolicyVersion:   decision.PolicyVersion,
+		ShadowDenied:    decision.ShadowDenied,
+		Schedule:        decision.Schedule,
+	}
+}
+
+// seconds rounds up, a client told to retry after 0 seconds would retry right away
+func seconds(duration time.Duration) int64 {
+	return int64((duration + time.Second - 1) / time.Second)
+}
+
+func batchResponse(response *logic.BatchResponse) *models.BatchResponse {
+	results := make([]models.BatchResult, len(response.Results))
+	for i, result := range response.Results {
+		results[i] = models.BatchResult{
+			Charged:    result.Charged,
+			Skipped:    result.Skipped,
+			RejectedBy: result.RejectedBy,
+			Error:      result.Error,
+		}
+		if result.Decision != nil {
+			results[i].LimiterResponse = limiterResponse(result.Decision)
+		}
+	}
+
+	return &models.BatchResponse{Allowed: response.Allowed, Results: results}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"goapp/constants"
+	"goapp/logger"
+	"goapp/logic"
+	"goapp/models"
+	"goapp/store"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gofiber/fiber/v2"
+)
+
+// policyError maps the errors of the policy logic to a response
+func policyError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, logic.ErrInvalidPolicy):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, store.ErrPolicyNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Policy not found",
+		})
+	case errors.Is(err, store.ErrPolicyExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Policy already exists",
+		})
+	case errors.Is(err, store.ErrVersionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Policy version not found",
+		})
+	case errors.Is(err, store.ErrVersionNotRestorable):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, store.ErrPolicyConflict):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Policy was modified since it was read, fetch it again and retry",
+		})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Internal server error while managing policies",
+	})
+}
+
+// policyActor is who the change is recorded under in the policy history
+func policyActor(c *fiber.Ctx) string {
+	if actor := c.Get(constants.HeaderActor); actor != "" {
+		return actor
+	}
+	return constants.DefaultActor
+}
+
+// RequirePolicyDatabase rejects the policy management requests when policies are read from a file, the file is
+// the source of truth there and is edited instead
+func (cfg *ConfigHandler) RequirePolicyDatabase(c *fiber.Ctx) error {
+	if cfg.policies == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"error": "Policies are managed through the policy file, the api needs the postgres policy store",
+		})
+	}
+	return c.Next()
+}
+
+func (cfg *ConfigHandler) GetPolicies(c *fiber.Ctx) error {
+	scope := c.Query(constants.KeyScope)
+	identifier := c.Query(constants.KeyIdentifier)
+
+	reqLog := logger.GetRequestLogger(c, cfg.log)
+
+	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
+	defer cancel()
+
+	// a full key asks for a single policy, otherwise the policies are listed
+	if scope != "" && identifier != "" {
+		record, err := logic.GetPolicy(ctx, cfg.policies, reqLog, scope, identifier)
+		if err != nil {
+			return policyError(c, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(record)
+	}
+
+	records, err := logic.ListPolicies(ctx, cfg.policies, reqLog, scope)
+	if err != nil {
+		return policyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"policies": records,
+	})
+}
+
+func (cfg *ConfigHandler) CreatePolicy(c *fiber.Ctx) error {
+	var policy models.PolicySchema
+	if err := sonic.Unmarshal(c.Body(), &policy); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid policy body",
+		})
+	}
+
+	reqLog := logger.GetRequestLogger(c, cfg.log)
+
+	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
+	defer cancel()
+
+	record, err := logic.CreatePolicy(ctx, cfg.policies, reqLog, cfg.cache, &policy, policyActor(c))
+	if err != nil {
+		return policyError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(record)
+}
+
+func (cfg *ConfigHandler) UpdatePolicy(c *fiber.Ctx) error {
+	var record store.PolicyRecord
+	if err := sonic.Unmarshal(c.Body(), &record); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid policy body",
+		})
+	}
+
+	if record.UpdatedAt.IsZero() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing updatedAt of the policy being replaced",
+		})
+	}
+
+	reqLog := logger.GetRequestLogger(c, cfg.log)
+
+	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
+	defer cancel()
+
+	updated, err := logic.UpdatePolicy(ctx, cfg.policies, reqLog, cfg.cache, &record.PolicySchema, record.UpdatedAt, policyActor(c))
+	if err != nil {
+		return policyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+func (cfg *ConfigHandler) DeletePolicy(c *fiber.Ctx) error {
+	scope := c.Query(constants.KeyScope)
+	identifier := c.Query(constants.KeyIdentifier)
+
+	if scope == "" || identifier == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required query parameters: scope and identifier",
+		})
+	}
+
+	// deleting without updatedAt removes the policy whatever its version
+	var expectedUpdatedAt *time.Time
+	if raw := c.Query(constants.KeyUpdatedAt); raw != "" {
+		updatedAt, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "updatedAt must be an RFC 3339 timestamp",
+			})
+		}
+		expectedUpdatedAt = &updatedAt
+	}
+
+	reqLog := logger.GetRequestLogger(c, cfg.log)
+
+	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
+	defer cancel()
+
+	if err := logic.DeletePolicy(ctx, cfg.policies, reqLog, cfg.cache, scope, identifier, expectedUpdatedAt, policyActor(c)); err != nil {
+		return policyError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (cfg *ConfigHandler) GetPolicyVersions(c *fiber.Ctx) error {
+	scope := c.Query(constants.KeyScope)
+	identifier := c.Query(constants.KeyIdentifier)
+
+	if scope == "" || identifier == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required query parameters: scope and identifier",
+		})
+	}
+
+	reqLog := logger.GetRequestLogger(c, cfg.log)
+
+	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
+	defer cancel()
+
+	versions, err := logic.ListPolicyVersions(ctx, cfg.policies, reqLog, scope, identifier)
+	if err != nil {
+		return policyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"versions": versions,
+	})
+}
+
+func (cfg *ConfigHandler) RollbackPolicy(c *fiber.Ctx) error {
+	scope := c.Query(constants.KeyScope)
+	identifier := c.Query(constants.KeyIdentifier)
+
+	version, err := strconv.ParseInt(c.Query(constants.KeyVersion), 10, 64)
+	if scope == "" || identifier == "" || err != nil || version <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required query parameters: scope, identifier and a positive version",
+		})
+	}
+
+	reqLog := logger.GetRequestLogger(c, cfg.log)
+
+	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
+	defer cancel()
+
+	record, err := logic.RollbackPolicy(ctx, cfg.policies, reqLog, cfg.cache, scope, identifier, version, policyActor(c))
+	if err != nil {
+		return policyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(record)
+}
+
+func (cfg *ConfigHandler) ValidatePolicy(c *fiber.Ctx) error {
+	var policy models.PolicySchema
+	if err := sonic.Unmarshal(c.Body(), &policy); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid policy body",
+		})
+	}
+
+	if err := logic.ValidatePolicy(&policy); err != nil {
+		return policyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"valid": true,
+	})
+}
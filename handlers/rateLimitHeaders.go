@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"goapp/constants"
+	"goapp/models"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultPolicyName names the limit in the IETF headers when neither a rule nor a level decided the request
+const defaultPolicyName = "default"
+
+var policyNameEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// setRateLimitHeaders tells the client where it stands against the limit that decided the request, in the header
+// set the deployment is configured for. Rejected requests always carry the standard Retry-After
+func (cfg *ConfigHandler) setRateLimitHeaders(c *fiber.Ctx, allowed *models.LimiterResponse) {
+	mode := cfg.config.RateLimitHeaders
+	if mode == "" || mode == constants.RateLimitHeadersLegacy || mode == constants.RateLimitHeadersBoth {
+		setLegacyHeaders(c, allowed)
+	}
+	if mode == constants.RateLimitHeadersIetf || mode == constants.RateLimitHeadersBoth {
+		setIetfHeaders(c, allowed)
+	}
+
+	if !allowed.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(allowed.RetryAfter, 10))
+	}
+	if allowed.Degraded {
+		c.Set("X-RateLimit-Degraded", allowed.Reason)
+	}
+}
+
+func setLegacyHeaders(c *fiber.Ctx, allowed *models.LimiterResponse) {
+	c.Set("X-RateLimit-Limit", strconv.FormatInt(allowed.TotalTokens, 10))
+	c.Set("X-RateLimit-Remaining", strconv.FormatInt(allowed.RemainingTokens, 10))
+	c.Set("X-RateLimit-Retry-After", strconv.FormatInt(allowed.RetryAfter, 10))
+}
+
+// setIetfHeaders follows draft-ietf-httpapi-ratelimit-headers, the policy is named after the rule or level that
+// decided the request and its window is left out for limits without one, like concurrency limits
+func setIetfHeaders(c *fiber.Ctx, allowed *models.LimiterResponse) {
+	name := allowed.Rule
+	if name == "" {
+		name = allowed.Level
+	}
+	if name == "" {
+		name = defaultPolicyName
+	}
+	name = `"` + policyNameEscaper.Replace(name) + `"`
+
+	policy := name + ";q=" + strconv.FormatInt(allowed.TotalTokens, 10)
+	if allowed.Window > 0 {
+		policy += ";w=" + strconv.FormatInt(allowed.Window, 10)
+	}
+
+	c.Set("RateLimit-Policy", policy)
+	c.Set("RateLimit", name+";r="+strconv.FormatInt(max(allowed.RemainingTokens, 0), 10)+";t="+strconv.FormatInt(allowed.Reset, 10))
+}
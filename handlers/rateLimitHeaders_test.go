@@ -0,0 +1,138 @@
+package handlers_test
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/handlers"
+	"goapp/logic"
+	"goapp/models"
+	"goapp/ratelimit"
+	"goapp/services"
+	"goapp/store"
+	"goapp/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// newLimiterApp serves the limiter endpoint in memory, with the headers of the given mode
+func newLimiterApp(t *testing.T, mode string, policies ...*models.PolicySchema) *fiber.App {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cache := services.NewCache(store.NewMemoryPolicyStore(policies...))
+	cache.LoadCache(ctx, zerolog.Nop())
+	limiters := logic.Limiters{constants.ValeTypeMemory: ratelimit.New(ratelimit.NewBackends(ctx, ratelimit.Options{}).Memory(), cache)}
+
+	config := &utils.Config{RateLimitHeaders: mode}
+	handler := handlers.NewConfigHandler(ctx, config, zerolog.Nop(), nil, nil, limiters, cache, nil)
+
+	app := fiber.New()
+	app.Get("/limiter", handler.GetLimiter)
+	return app
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	slidingLog := &models.PolicySchema{Scope: "api", Identifier: "*", Limit: 2, Window: "1m", Algorithm: constants.AlgorithmSlidingLog}
+	multiRule := &models.PolicySchema{Scope: "api", Identifier: "*", Algorithm: constants.AlgorithmMultiWindow, Rules: []models.LimitRule{
+		{Name: "hour", Limit: 100, Window: "1h"},
+		{Name: "second", Limit: 2, Window: "1s"},
+	}}
+	concurrency := &models.PolicySchema{Scope: "api", Identifier: "*", Limit: 3, Window: "1m", Algorithm: constants.AlgorithmConcurrency}
+
+	tests := []struct {
+		name     string
+		mode     string
+		policy   *models.PolicySchema
+		requests int
+		status   int
+		// headers that must be absent are expected empty
+		headers map[string]string
+	}{
+		{
+			name:     "legacy by default",
+			policy:   slidingLog,
+			requests: 1,
+			status:   http.StatusOK,
+			headers: map[string]string{
+				"X-RateLimit-Limit": "2", "X-RateLimit-Remaining": "1", "X-RateLimit-Retry-After": "0",
+				"RateLimit": "", "RateLimit-Policy": "", "Retry-After": "",
+			},
+		},
+		{
+			name:     "ietf",
+			mode:     constants.RateLimitHeadersIetf,
+			policy:   slidingLog,
+			requests: 1,
+			status:   http.StatusOK,
+			headers: map[string]string{
+				"RateLimit": `"default";r=1;t=60`, "RateLimit-Policy": `"default";q=2;w=60`,
+				"X-RateLimit-Limit": "", "Retry-After": "",
+			},
+		},
+		{
+			name:     "ietf on a rejected request",
+			mode:     constants.RateLimitHeadersIetf,
+			policy:   slidingLog,
+			requests: 3,
+			status:   http.StatusTooManyRequests,
+			headers: map[string]string{
+				"RateLimit": `"default";r=0;t=60`, "RateLimit-Policy": `"default";q=2;w=60`, "Retry-After": "60",
+			},
+		},
+		{
+			name:     "both",
+			mode:     constants.RateLimitHeadersBoth,
+			policy:   slidingLog,
+			requests: 3,
+			status:   http.StatusTooManyRequests,
+			headers: map[string]string{
+				"X-RateLimit-Limit": "2", "X-RateLimit-Remaining": "0", "X-RateLimit-Retry-After": "60",
+				"RateLimit-Policy": `"default";q=2;w=60`, "Retry-After": "60",
+			},
+		},
+		{
+			name:     "policy named after the binding rule",
+			mode:     constants.RateLimitHeadersIetf,
+			policy:   multiRule,
+			requests: 1,
+			status:   http.StatusOK,
+			headers:  map[string]string{"RateLimit-Policy": `"second";q=2;w=1`},
+		},
+		{
+			name:     "no window for concurrency limits",
+			mode:     constants.RateLimitHeadersIetf,
+			policy:   concurrency,
+			requests: 1,
+			status:   http.StatusOK,
+			headers:  map[string]string{"RateLimit-Policy": `"default";q=3`, "RateLimit": `"default";r=2;t=60`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newLimiterApp(t, tt.mode, tt.policy)
+
+			var response *http.Response
+			for range tt.requests {
+				var err error
+				response, err = app.Test(httptest.NewRequest(http.MethodGet, "/limiter?scope=api&identifier=a&type="+constants.ValeTypeMemory, nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if response.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, response.StatusCode)
+			}
+			for header, expected := range tt.headers {
+				if value := response.Header.Get(header); value != expected {
+					t.Fatalf("expected %s to be %q, got %q", header, expected, value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"goapp/constants"
+	"goapp/logger"
+	"goapp/logic"
+	"goapp/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (cfg *ConfigHandler) ReleaseLease(c *fiber.Ctx) error {
+	queries := c.Queries()
+	scope := queries[constants.KeyScope]
+	identifier := queries[constants.KeyIdentifier]
+	rateLimitType := queries[constants.KeyRateLimitType]
+	leaseId := queries[constants.KeyLeaseId]
+
+	if scope == "" || identifier == "" || leaseId == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing required query parameters: scope, identifier and leaseId",
+		})
+	}
+
+	reqLog := logger.GetRequestLogger(c, cfg.log)
+
+	// Create a timeout-bounded context for the request
+	ctx, cancel := context.WithTimeout(c.Context(), constants.RequestTimeout)
+	defer cancel()
+
+	released, err := logic.ReleaseLease(ctx, reqLog, cfg.limiters, scope, identifier, rateLimitType, leaseId)
+	if errors.Is(err, ratelimit.ErrReleaseUnsupported) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "The policy does not use a concurrency limiter",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error while releasing the lease",
+		})
+	}
+
+	if !released {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"released": false,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"released": true,
+	})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"goapp/constants"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/gofiber/fiber/v2"
+)
+
+type costBody struct {
+	Cost *int64 `json:"cost"`
+}
+
+var errInvalidCost = errors.New("cost must be a positive integer")
+
+// parseCost reads the number of units the request consumes from the query string or the json body
+func parseCost(c *fiber.Ctx) (int64, error) {
+	if raw := c.Query(constants.KeyCost); raw != "" {
+		cost, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || cost <= 0 {
+			return 0, errInvalidCost
+		}
+		return cost, nil
+	}
+
+	if len(c.Body()) > 0 {
+		var body costBody
+		if err := sonic.Unmarshal(c.Body(), &body); err != nil {
+			return 0, errInvalidCost
+		}
+		if body.Cost != nil {
+			if *body.Cost <= 0 {
+				return 0, errInvalidCost
+			}
+			return *body.Cost, nil
+		}
+	}
+
+	return constants.DefaultCost, nil
+}
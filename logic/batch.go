@@ -0,0 +1,185 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapp/constants"
+	"goapp/models"
+	"goapp/ratelimit"
+	"goapp/services"
+	"goapp/store"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// BatchResult is the decision for one item, or why no decision could be made for it. Charged tells whether the cost
+// stays charged to the item, an all-or-nothing batch hands it back to every item once one rejects the request, and
+// RejectedBy is the index of that item. The items after it are Skipped, they carry no decision and were never charged
+type BatchResult struct {
+	Decision   *ratelimit.Decision
+	Charged    bool
+	Skipped    bool
+	RejectedBy *int
+	Error      string
+}
+
+// BatchResponse holds the results in the order of the items, Allowed only when every item was allowed
+type BatchResponse struct {
+	Allowed bool
+	Results []BatchResult
+}
+
+const batchItemError = "Error evaluating the rate limit"
+
+// CheckBatch evaluates every item of the batch. Items are evaluated concurrently and their redis scripts sent in a
+// single pipeline, unless the batch is all-or-nothing in which case they are checked one after the other and the items
+// that admitted the request are refunded as soon as one rejects it, so it is charged to all of them or none
+func CheckBatch(ctx context.Context, rdb *redis.Client, log zerolog.Logger, limiters Limiters, cache *services.Cache, request *models.BatchRequest) (*BatchResponse, error) {
+	items, err := validateBatch(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []BatchResult
+	if request.AllOrNothing {
+		results = checkAllOrNothing(ctx, log, limiters, cache, items)
+	} else {
+		results = checkEach(ctx, rdb, log, limiters, items)
+	}
+
+	response := &BatchResponse{Allowed: true, Results: results}
+	for _, result := range results {
+		if result.Decision == nil || result.Error != "" || !result.Decision.Allowed {
+			response.Allowed = false
+		}
+	}
+	return response, nil
+}
+
+// validateBatch returns the items with their default cost filled in
+func validateBatch(request *models.BatchRequest) ([]models.BatchItem, error) {
+	if len(request.Items) == 0 || len(request.Items) > constants.MaxBatchItems {
+		return nil, fmt.Errorf("%w : a batch holds between 1 and %d items", ErrInvalidBatch, constants.MaxBatchItems)
+	}
+
+	items := make([]models.BatchItem, len(request.Items))
+	keys := make(map[string]bool, len(request.Items))
+	for i, item := range request.Items {
+		if item.Scope == "" || item.Identifier == "" {
+			return nil, fmt.Errorf("%w : item %d is missing its scope or identifier", ErrInvalidBatch, i)
+		}
+		if item.Cost < 0 {
+			return nil, fmt.Errorf("%w : item %d has a negative cost", ErrInvalidBatch, i)
+		}
+		if item.Cost == 0 {
+			item.Cost = constants.DefaultCost
+		}
+
+		// an all-or-nothing batch would charge a key checked twice once and refund it twice
+		if request.AllOrNothing {
+			key := item.Scope + ":" + item.Identifier
+			if keys[key] {
+				return nil, fmt.Errorf("%w : %s is checked twice", ErrInvalidBatch, key)
+			}
+			keys[key] = true
+		}
+
+		items[i] = item
+	}
+
+	return items, nil
+}
+
+func checkEach(ctx context.Context, rdb *redis.Client, log zerolog.Logger, limiters Limiters, items []models.BatchItem) []BatchResult {
+	results := make([]BatchResult, len(items))
+	batchCtx, batch := store.NewPipelineBatch(ctx, rdb, len(items))
+
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer batch.Done()
+
+			itemLog := log.With().Str("scope", item.Scope).Str("identifier", item.Identifier).Logger()
+			response, err := GetLimiter(batchCtx, itemLog, limiters, item.Scope, item.Identifier, item.Type, item.Cost)
+			if err != nil {
+				itemLog.Error().Err(err).Msg("Error evaluating the batch item")
+				results[i] = BatchResult{Error: batchItemError}
+				return
+			}
+			results[i] = BatchResult{Decision: response, Charged: response.Allowed}
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+func checkAllOrNothing(ctx context.Context, log zerolog.Logger, limiters Limiters, cache *services.Cache, items []models.BatchItem) []BatchResult {
+	results := make([]BatchResult, len(items))
+
+	// an item without a policy or limiter fails the whole batch before anything is charged
+	checks := make([]ratelimit.Check, len(items))
+	invalid := false
+	for i, item := range items {
+		itemLog := log.With().Str("scope", item.Scope).Str("identifier", item.Identifier).Logger()
+
+		limiter, err := limiters.forType(item.Type)
+		if err != nil {
+			itemLog.Error().Err(err).Msg("Error getting the limiter interface")
+			results[i].Error = batchItemError
+			invalid = true
+			continue
+		}
+		if _, exists := cache.GetPolicy(ctx, log, item.Scope, item.Identifier); !exists {
+			itemLog.Error().Msg("No policy found for the batch item")
+			results[i].Error = batchItemError
+			invalid = true
+			continue
+		}
+
+		checks[i] = ratelimit.Check{Limiter: limiter, Key: ratelimit.Key(item.Scope, item.Identifier), Cost: item.Cost}
+	}
+	if invalid {
+		return results
+	}
+
+	decisions, err := ratelimit.AllowAll(log.WithContext(ctx), checks)
+	if err != nil {
+		log.Error().Err(err).Msg("Error evaluating the all-or-nothing batch")
+		for i := range results {
+			results[i].Error = batchItemError
+		}
+		return results
+	}
+
+	// the decisions stop at the item that rejected the batch, the items before it were refunded and the ones after it
+	// were never checked
+	rejectedBy := len(decisions) - 1
+	if decisions[rejectedBy].Allowed {
+		for i, decision := range decisions {
+			results[i] = BatchResult{Decision: decision, Charged: true}
+		}
+		return results
+	}
+
+	for i := range results {
+		results[i].RejectedBy = &rejectedBy
+		if i < len(decisions) {
+			// the lease of a refunded item was released with it
+			if i < rejectedBy {
+				decisions[i].LeaseId = ""
+			}
+			results[i].Decision = decisions[i]
+		} else {
+			results[i].Skipped = true
+		}
+	}
+	return results
+}
@@ -0,0 +1,113 @@
+package logic_test
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/logic"
+	"goapp/models"
+	"goapp/ratelimit"
+	"goapp/services"
+	"goapp/store"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestCheckBatchAllOrNothing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := services.NewCache(store.NewMemoryPolicyStore(
+		&models.PolicySchema{Scope: "ip", Identifier: "*", Limit: 3, Window: "1m", Algorithm: constants.AlgorithmFixedWindow},
+		&models.PolicySchema{Scope: "key", Identifier: "*", Limit: 2, Window: "1m", Algorithm: constants.AlgorithmFixedWindow},
+		&models.PolicySchema{Scope: "tenant", Identifier: "*", Limit: 10, Window: "1m", Algorithm: constants.AlgorithmFixedWindow},
+	))
+	backends := ratelimit.NewBackends(ctx, ratelimit.Options{})
+	limiters := logic.Limiters{constants.ValeTypeMemory: ratelimit.New(backends.Memory(), cache)}
+
+	batch := &models.BatchRequest{AllOrNothing: true, Items: []models.BatchItem{
+		{Scope: "ip", Identifier: "a", Type: constants.ValeTypeMemory},
+		{Scope: "key", Identifier: "a", Type: constants.ValeTypeMemory},
+		{Scope: "tenant", Identifier: "a", Type: constants.ValeTypeMemory},
+	}}
+
+	type item struct {
+		allowed, charged, skipped bool
+		remaining                 int64
+	}
+
+	tests := []struct {
+		name       string
+		allowed    bool
+		rejectedBy int
+		items      []item
+	}{
+		{
+			name:       "first batch is charged to every item",
+			allowed:    true,
+			rejectedBy: -1,
+			items:      []item{{true, true, false, 2}, {true, true, false, 1}, {true, true, false, 9}},
+		},
+		{
+			name:       "second batch uses up the key",
+			allowed:    true,
+			rejectedBy: -1,
+			items:      []item{{true, true, false, 1}, {true, true, false, 0}, {true, true, false, 8}},
+		},
+		{
+			// the ip admitted the request before the key rejected it, it is refunded and the tenant is never checked
+			name:       "key rejects the batch",
+			allowed:    false,
+			rejectedBy: 1,
+			items:      []item{{true, false, false, 0}, {false, false, false, 0}, {false, false, true, 0}},
+		},
+		{
+			name:       "the refunded ip is charged again",
+			allowed:    false,
+			rejectedBy: 1,
+			items:      []item{{true, false, false, 0}, {false, false, false, 0}, {false, false, true, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := logic.CheckBatch(ctx, nil, zerolog.Nop(), limiters, cache, batch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Allowed != tt.allowed {
+				t.Fatalf("expected the batch to be allowed %v, got %v", tt.allowed, response.Allowed)
+			}
+
+			for i, expected := range tt.items {
+				result := response.Results[i]
+				if result.Error != "" {
+					t.Fatalf("item %d failed : %s", i, result.Error)
+				}
+				if result.Charged != expected.charged || result.Skipped != expected.skipped {
+					t.Fatalf("item %d : expected charged %v and skipped %v, got %+v", i, expected.charged, expected.skipped, result)
+				}
+
+				if tt.rejectedBy < 0 && result.RejectedBy != nil {
+					t.Fatalf("item %d : allowed batch reports it was rejected by item %d", i, *result.RejectedBy)
+				}
+				if tt.rejectedBy >= 0 && (result.RejectedBy == nil || *result.RejectedBy != tt.rejectedBy) {
+					t.Fatalf("item %d : expected the batch to be rejected by item %d, got %v", i, tt.rejectedBy, result.RejectedBy)
+				}
+
+				if expected.skipped {
+					if result.Decision != nil {
+						t.Fatalf("item %d : skipped item carries a decision %+v", i, result.Decision)
+					}
+					continue
+				}
+				if result.Decision.Allowed != expected.allowed {
+					t.Fatalf("item %d : expected its own decision to be allowed %v, got %+v", i, expected.allowed, result.Decision)
+				}
+				if expected.allowed && result.Decision.Remaining != expected.remaining {
+					t.Fatalf("item %d : expected %d remaining, got %d", i, expected.remaining, result.Decision.Remaining)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"goapp/algorithms"
+	"goapp/constants"
+	"goapp/models"
+	"goapp/ratelimit"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ForwardedRequest is what the proxy tells about the original request
+type ForwardedRequest struct {
+	Path   string
+	Ip     string
+	Header func(name string) string
+}
+
+type ForwardAuthRule struct {
+	scope   string
+	source  string
+	header  string
+	pattern *regexp.Regexp
+}
+
+// NewForwardAuthRules checks the configured rules once at startup so that a typo fails the start and not every request
+func NewForwardAuthRules(forwardAuth models.ForwardAuth) ([]*ForwardAuthRule, error) {
+	if forwardAuth.TrustedProxies < 0 {
+		return nil, fmt.Errorf("forward-auth trusted proxies cannot be negative")
+	}
+
+	switch forwardAuth.FailureMode {
+	case "", constants.FailureModeOpen, constants.FailureModeClosed:
+	default:
+		return nil, fmt.Errorf("forward-auth has an unknown failure mode %q, use open or closed", forwardAuth.FailureMode)
+	}
+
+	compiled := make([]*ForwardAuthRule, 0, len(forwardAuth.Rules))
+	for i, rule := range forwardAuth.Rules {
+		if rule.Scope == "" || strings.Contains(rule.Scope, ":") {
+			return nil, fmt.Errorf("forward-auth rule %d needs a scope without ':'", i)
+		}
+
+		switch rule.Source {
+		case constants.ForwardAuthSourceHeader:
+			if rule.Header == "" {
+				return nil, fmt.Errorf("forward-auth rule %d reads a header but does not name it", i)
+			}
+		case constants.ForwardAuthSourcePath, constants.ForwardAuthSourceIp:
+		default:
+			return nil, fmt.Errorf("forward-auth rule %d has an unknown source %q, use header, path or ip", i, rule.Source)
+		}
+
+		var pattern *regexp.Regexp
+		if rule.Pattern != "" {
+			var err error
+			pattern, err = regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("forward-auth rule %d has an invalid pattern : %w", i, err)
+			}
+		}
+
+		compiled = append(compiled, &ForwardAuthRule{
+			scope:   rule.Scope,
+			source:  rule.Source,
+			header:  http.CanonicalHeaderKey(rule.Header),
+			pattern: pattern,
+		})
+	}
+
+	return compiled, nil
+}
+
+// ForwardAuthKey returns the scope and identifier of the first rule the request has a value for
+func ForwardAuthKey(rules []*ForwardAuthRule, request ForwardedRequest) (string, string, bool) {
+	for _, rule := range rules {
+		var value string
+		switch rule.source {
+		case constants.ForwardAuthSourceHeader:
+			value = request.Header(rule.header)
+		case constants.ForwardAuthSourcePath:
+			value = request.Path
+		case constants.ForwardAuthSourceIp:
+			value = request.Ip
+		}
+
+		if value != "" && rule.pattern != nil {
+			match := rule.pattern.FindStringSubmatch(value)
+			switch {
+			case match == nil:
+				value = ""
+			case len(match) > 1:
+				value = match[1]
+			default:
+				value = match[0]
+			}
+		}
+
+		if value != "" {
+			return rule.scope, value, true
+		}
+	}
+
+	return "", "", false
+}
+
+// ForwardAuthFailure is the decision on a request the limiter failed on, the proxy treats an error as a rejection
+// so it is turned into a degraded allow or, failing closed, a degraded deny
+func ForwardAuthFailure(failureMode string, err error) *ratelimit.Decision {
+	reason := algorithms.FailureReason(err)
+	if errors.Is(err, ratelimit.ErrNoPolicy) {
+		reason = constants.ReasonNoPolicy
+	}
+
+	return &ratelimit.Decision{
+		Allowed:  failureMode != constants.FailureModeClosed,
+		Degraded: true,
+		Reason:   reason,
+	}
+}
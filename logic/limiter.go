@@ -2,22 +2,57 @@ package logic
 
 import (
 	"context"
-	"goapp/algorithms"
-	"goapp/models"
-	"goapp/services"
-	"goapp/store"
-	"goapp/utils"
+	"fmt"
+	"goapp/ratelimit"
 
-	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 )
 
-func GetLimiter(ctx context.Context, db *store.Db, rdb *redis.Client, config *utils.Config, log zerolog.Logger, limiterFactory algorithms.LimiterFactory, cache *services.Cache, cb *services.CircuitBreaker, scope, identifier, rateLimitType string) (*models.LimiterResponse, error) {
-	limiter, err := limiterFactory.GetLimiter(ctx, db, log, scope, identifier, rateLimitType, config.Queries.Fetch.FetchPolicyByKey, cache)
+// Limiters are the limiters the service decides with, keyed by the limiter type a request asks for
+type Limiters map[string]*ratelimit.PolicyLimiter
+
+func (l Limiters) forType(rateLimitType string) (*ratelimit.PolicyLimiter, error) {
+	limiter, ok := l[rateLimitType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported limiter type: %s", rateLimitType)
+	}
+	return limiter, nil
+}
+
+func GetLimiter(ctx context.Context, log zerolog.Logger, limiters Limiters, scope, identifier, rateLimitType string, cost int64) (*ratelimit.Decision, error) {
+	limiter, err := limiters.forType(rateLimitType)
 	if err != nil {
 		log.Error().Err(err).Msg("Error getting the limiter interface")
 		return nil, err
 	}
 
-	return limiter.Allow(ctx, rdb, cb, log, scope, identifier)
+	response, err := limiter.Allow(log.WithContext(ctx), ratelimit.Key(scope, identifier), cost)
+	if err != nil && response == nil {
+		log.Error().Err(err).Msg("Error evaluating the rate limit")
+	}
+	return response, err
+}
+
+func GetHierarchicalLimiter(ctx context.Context, log zerolog.Logger, limiters Limiters, levels []ratelimit.Level, rateLimitType string, cost int64) (*ratelimit.Decision, error) {
+	limiter, err := limiters.forType(rateLimitType)
+	if err != nil {
+		log.Error().Err(err).Msg("Error getting the hierarchy limiter")
+		return nil, err
+	}
+
+	response, err := limiter.AllowLevels(log.WithContext(ctx), levels, cost)
+	if err != nil && response == nil {
+		log.Error().Err(err).Msg("Error evaluating the hierarchy")
+	}
+	return response, err
+}
+
+func ReleaseLease(ctx context.Context, log zerolog.Logger, limiters Limiters, scope, identifier, rateLimitType, leaseId string) (bool, error) {
+	limiter, err := limiters.forType(rateLimitType)
+	if err != nil {
+		log.Error().Err(err).Msg("Error getting the limiter interface")
+		return false, err
+	}
+
+	return limiter.Release(log.WithContext(ctx), ratelimit.Key(scope, identifier), leaseId)
 }
@@ -0,0 +1,104 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapp/algorithms"
+	"goapp/models"
+	"goapp/services"
+	"goapp/store"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrInvalidPolicy wraps the validation failures of a policy
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+func ValidatePolicy(policy *models.PolicySchema) error {
+	if err := algorithms.ValidatePolicy(policy); err != nil {
+		return fmt.Errorf("%w : %w", ErrInvalidPolicy, err)
+	}
+	return nil
+}
+
+func ListPolicies(ctx context.Context, policies store.VersionedPolicyRepository, log zerolog.Logger, scope string) ([]*store.PolicyRecord, error) {
+	records, err := policies.Records(ctx, log)
+	if err != nil || scope == "" {
+		return records, err
+	}
+
+	filtered := make([]*store.PolicyRecord, 0, len(records))
+	for _, record := range records {
+		if record.Scope == scope {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered, nil
+}
+
+func GetPolicy(ctx context.Context, policies store.VersionedPolicyRepository, log zerolog.Logger, scope, identifier string) (*store.PolicyRecord, error) {
+	policy := models.PolicySchema{Scope: scope, Identifier: identifier}
+	return policies.Record(ctx, log, policy.Key())
+}
+
+func CreatePolicy(ctx context.Context, policies store.VersionedPolicyRepository, log zerolog.Logger, cache *services.Cache, policy *models.PolicySchema, actor string) (*store.PolicyRecord, error) {
+	if err := ValidatePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	updatedAt, err := policies.Create(ctx, log, policy, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	// the key may have been cached as missing or resolved to a pattern
+	cache.Invalidate(policy)
+
+	return &store.PolicyRecord{PolicySchema: *policy, UpdatedAt: updatedAt}, nil
+}
+
+// UpdatePolicy replaces the policy as long as nobody wrote it after expectedUpdatedAt
+func UpdatePolicy(ctx context.Context, policies store.VersionedPolicyRepository, log zerolog.Logger, cache *services.Cache, policy *models.PolicySchema, expectedUpdatedAt time.Time, actor string) (*store.PolicyRecord, error) {
+	if err := ValidatePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	updatedAt, err := policies.Update(ctx, log, policy, expectedUpdatedAt, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Invalidate(policy)
+
+	return &store.PolicyRecord{PolicySchema: *policy, UpdatedAt: updatedAt}, nil
+}
+
+func DeletePolicy(ctx context.Context, policies store.VersionedPolicyRepository, log zerolog.Logger, cache *services.Cache, scope, identifier string, expectedUpdatedAt *time.Time, actor string) error {
+	policy := &models.PolicySchema{Scope: scope, Identifier: identifier}
+
+	if err := policies.Remove(ctx, log, policy.Key(), expectedUpdatedAt, actor); err != nil {
+		return err
+	}
+
+	cache.Invalidate(policy)
+	return nil
+}
+
+func ListPolicyVersions(ctx context.Context, policies store.VersionedPolicyRepository, log zerolog.Logger, scope, identifier string) ([]*store.PolicyVersion, error) {
+	policy := models.PolicySchema{Scope: scope, Identifier: identifier}
+	return policies.Versions(ctx, log, policy.Key())
+}
+
+func RollbackPolicy(ctx context.Context, policies store.VersionedPolicyRepository, log zerolog.Logger, cache *services.Cache, scope, identifier string, version int64, actor string) (*store.PolicyRecord, error) {
+	policy := &models.PolicySchema{Scope: scope, Identifier: identifier}
+
+	record, err := policies.Rollback(ctx, log, policy.Key(), version, actor, ValidatePolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Invalidate(policy)
+	return record, nil
+}
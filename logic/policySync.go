@@ -0,0 +1,45 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapp/models"
+	"goapp/store"
+
+	"github.com/rs/zerolog"
+)
+
+// ValidatePolicies checks every policy of a policy file and that no key appears twice, all problems are reported at once
+func ValidatePolicies(policies []*models.PolicySchema) error {
+	var errs []error
+	seen := make(map[string]bool, len(policies))
+
+	for i, policy := range policies {
+		if err := ValidatePolicy(policy); err != nil {
+			errs = append(errs, fmt.Errorf("policy %d (%s) : %w", i, policy.Key(), err))
+			continue
+		}
+
+		if seen[policy.Key()] {
+			errs = append(errs, fmt.Errorf("%w : policy %s is defined more than once", ErrInvalidPolicy, policy.Key()))
+		}
+		seen[policy.Key()] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+func PlanPolicySync(ctx context.Context, repository store.VersionedPolicyRepository, log zerolog.Logger, policies []*models.PolicySchema, prune bool) ([]*store.PolicyChange, error) {
+	if err := ValidatePolicies(policies); err != nil {
+		return nil, err
+	}
+	return repository.Plan(ctx, log, policies, prune)
+}
+
+func ApplyPolicySync(ctx context.Context, repository store.VersionedPolicyRepository, log zerolog.Logger, policies []*models.PolicySchema, prune bool, actor string) ([]*store.PolicyChange, error) {
+	if err := ValidatePolicies(policies); err != nil {
+		return nil, err
+	}
+	return repository.Sync(ctx, log, policies, prune, actor)
+}
@@ -0,0 +1,72 @@
+package lua
+
+func GetConcurrencyAcquireScript() string {
+	script := `
+	local key = KEYS[1]
+	local slots_key = KEYS[2]
+
+	local capacity = tonumber(ARGV[1])
+	local ttl = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local requested = tonumber(ARGV[4])
+	local lease_id = ARGV[5]
+
+	-- Drop the leases of clients that never released them
+	local expired = redis.call("ZRANGEBYSCORE", key, "-inf", now)
+	for _, expired_id in ipairs(expired) do
+		redis.call("HDEL", slots_key, expired_id)
+	end
+	redis.call("ZREMRANGEBYSCORE", key, "-inf", now)
+
+	local in_flight = 0
+	for _, slots in ipairs(redis.call("HVALS", slots_key)) do
+		in_flight = in_flight + tonumber(slots)
+	end
+
+	local allowed = 0
+	local retry_after = 0
+
+	if in_flight + requested <= capacity then
+		redis.call("ZADD", key, now + ttl, lease_id)
+		redis.call("HSET", slots_key, lease_id, requested)
+		in_flight = in_flight + requested
+		allowed = 1
+	else
+		-- the earliest slot frees up when the oldest lease expires, unless it is released before
+		local first = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+		if first[2] then
+			retry_after = tonumber(first[2]) - now
+		end
+	end
+
+	-- keep the keys as long as the longest living lease, every slot is free again by then at the latest
+	local reset = 0
+	local last = redis.call("ZRANGE", key, -1, -1, "WITHSCORES")
+	if last[2] then
+		reset = tonumber(last[2]) - now
+		local keep = math.max(1, reset)
+		redis.call("PEXPIRE", key, keep)
+		redis.call("PEXPIRE", slots_key, keep)
+	end
+
+	return {allowed, capacity - in_flight, retry_after, reset}
+	`
+
+	return script
+}
+
+func GetConcurrencyReleaseScript() string {
+	script := `
+	local key = KEYS[1]
+	local slots_key = KEYS[2]
+
+	local lease_id = ARGV[1]
+
+	local removed = redis.call("ZREM", key, lease_id)
+	redis.call("HDEL", slots_key, lease_id)
+
+	return removed
+	`
+
+	return script
+}
@@ -15,9 +15,9 @@ func GetFixedWindowCounterScript() string {
 	local tokens = tonumber(result[1])
 	local windowIndex = tonumber(result[2])
 
-	local currentWindowIndex = math.ceil(now / window)
+	local currentWindowIndex = math.floor(now / window)
 
-	if tokens == nil or currentWindowIndex > windowIndex then
+	if tokens == nil or currentWindowIndex ~= windowIndex then
 		tokens = capacity
 		windowIndex = currentWindowIndex
 	end
@@ -29,13 +29,42 @@ func GetFixedWindowCounterScript() string {
 		tokens = tokens - requested
 	end
 
+	-- the window is refilled once it ends, denied requests have to wait until then
+	local reset = ((windowIndex + 1) * window) - now
+
 	-- Store the values
 	redis.call("HMSET", key, "tokens", tokens, "windowIndex", windowIndex)
 
 	-- SET TTL ( PEXPIRE is crucial as it accepts the time in milliseconds)
-	redis.call("PEXPIRE", key, window)
+	redis.call("PEXPIRE", key, math.max(1, reset))
+
+	return {allowed, tokens, reset}
+	`
+
+	return script
+}
+
+func GetFixedWindowRefundScript() string {
+	script := `
+	local key = KEYS[1]
+
+	local capacity = tonumber(ARGV[1])
+	local window = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local refunded = tonumber(ARGV[4])
+
+	local result = redis.call("HMGET", key, "tokens", "windowIndex")
+	local tokens = tonumber(result[1])
+	local windowIndex = tonumber(result[2])
+
+	-- a window that has ended since is refilled anyway, there is nothing left to hand back
+	if tokens == nil or windowIndex ~= math.floor(now / window) then
+		return 0
+	end
+
+	redis.call("HSET", key, "tokens", math.min(capacity, tokens + refunded))
 
-	return allowed, tokens
+	return 1
 	`
 
 	return script
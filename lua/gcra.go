@@ -0,0 +1,71 @@
+package lua
+
+func GetGCRAScript() string {
+	script := `
+	local key = KEYS[1]
+
+	local emission_interval = tonumber(ARGV[1])
+	local tolerance = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local requested = tonumber(ARGV[4])
+
+	-- Fetch the theoretical arrival time
+	local tat = tonumber(redis.call("GET", key))
+	if tat == nil or tat < now then
+		tat = now
+	end
+
+	local new_tat = tat + (requested * emission_interval)
+	local allow_at = new_tat - tolerance
+
+	local allowed = 0
+	local retry_after = 0
+	local remaining
+
+	-- the whole burst is available again once the tat is back to now
+	local reset = tat - now
+
+	if now < allow_at then
+		retry_after = allow_at - now
+		remaining = math.floor((tolerance - (tat - now)) / emission_interval)
+	else
+		allowed = 1
+		reset = new_tat - now
+		remaining = math.floor((tolerance - (new_tat - now)) / emission_interval)
+
+		-- the key is only needed until the tat is back in the past
+		redis.call("SET", key, new_tat, "PX", math.max(1, math.ceil((new_tat - now) / 1000)))
+	end
+
+	return {allowed, math.max(remaining, 0), math.ceil(retry_after), math.ceil(reset)}
+	`
+
+	return script
+}
+
+func GetGCRARefundScript() string {
+	script := `
+	local key = KEYS[1]
+
+	local emission_interval = tonumber(ARGV[1])
+	local now = tonumber(ARGV[2])
+	local refunded = tonumber(ARGV[3])
+
+	local tat = tonumber(redis.call("GET", key))
+	if tat == nil then
+		return 0
+	end
+
+	-- move the tat back by the emission of the refunded requests, once it is in the past the key is not needed
+	tat = tat - (refunded * emission_interval)
+	if tat <= now then
+		redis.call("DEL", key)
+	else
+		redis.call("SET", key, tat, "PX", math.max(1, math.ceil((tat - now) / 1000)))
+	end
+
+	return 1
+	`
+
+	return script
+}
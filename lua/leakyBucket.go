@@ -26,16 +26,19 @@ func GetLeakyBucketScript() string {
 	last_leak = now
 
 	local allowed = 0
-	local retry_after
+	local retry_after = 0
 
 	if tokens + requested > capacity then
-		-- reject the requests at this stage
+		-- reject the requests at this stage, a request larger than the bucket waits for it to drain entirely
 		allowed = 0
+		retry_after = (tokens + math.min(requested, capacity) - capacity) / leak_rate
 	else
 		tokens = tokens + requested
 		allowed = 1
 	end
 
+	local reset = tokens / leak_rate
+
 	-- Save the state
 	redis.call("HMSET", key, "tokens", tokens, "last_leak", now)
 
@@ -43,7 +46,27 @@ func GetLeakyBucketScript() string {
 	local ttl = math.ceil((capacity / leak_rate) * 2)
 	redis.call("EXPIRE", key, ttl)
 
-	return {allowed, tokens}
+	-- the room left and the waits in milliseconds, redis truncates lua numbers to integers
+	return {allowed, math.floor(capacity - tokens), math.ceil(retry_after * 1000), math.ceil(reset * 1000)}
+	`
+
+	return script
+}
+
+func GetLeakyBucketRefundScript() string {
+	script := `
+	local key = KEYS[1]
+
+	local refunded = tonumber(ARGV[1])
+
+	local tokens = tonumber(redis.call("HGET", key, "tokens"))
+	if tokens == nil then
+		return 0
+	end
+
+	redis.call("HSET", key, "tokens", math.max(0, tokens - refunded))
+
+	return 1
 	`
 
 	return script
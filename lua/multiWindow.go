@@ -0,0 +1,95 @@
+package lua
+
+func GetMultiWindowScript() string {
+	script := `
+	local now = tonumber(ARGV[1])
+	local requested = tonumber(ARGV[2])
+
+	-- time until every counted request has faded out of the weighted count
+	local function reset_after(currentCnt, previousCnt, elapsed, window)
+		if currentCnt > 0 then
+			return (2 * window) - elapsed
+		elseif previousCnt > 0 then
+			return window - elapsed
+		end
+		return 0
+	end
+
+	local states = {}
+	local allowed = 1
+	local binding = 0
+	local binding_remaining = -1
+	local binding_retry = 0
+	local binding_reset = 0
+
+	-- Check every rule before consuming from any of them
+	for i = 1, #KEYS do
+		local capacity = tonumber(ARGV[1 + (i * 2)])
+		local window = tonumber(ARGV[2 + (i * 2)])
+
+		local result = redis.call("HMGET", KEYS[i], "currentCnt", "previousCnt", "windowStart")
+		local currentCnt = tonumber(result[1]) or 0
+		local previousCnt = tonumber(result[2]) or 0
+		local windowStart = tonumber(result[3]) or now
+
+		-- Shift windows if the current window has passed
+		local elapsed = now - windowStart
+		if elapsed >= window then
+			local shift = math.floor(elapsed / window)
+
+			if shift >= 2 then
+				previousCnt = 0
+			else
+				previousCnt = currentCnt
+			end
+
+			currentCnt = 0
+			windowStart = windowStart + (shift * window)
+			elapsed = now - windowStart
+		end
+
+		local effectiveCnt = currentCnt + (previousCnt * ((window - elapsed) / window))
+		states[i] = {currentCnt, previousCnt, windowStart, window}
+
+		if effectiveCnt + requested > capacity then
+			-- time until the weighted count leaves enough room for the request
+			local retry_after = window
+			if requested <= capacity and currentCnt + requested <= capacity then
+				retry_after = (window * (1 - ((capacity - currentCnt - requested) / previousCnt))) - elapsed
+			elseif requested <= capacity then
+				retry_after = (window - elapsed) + (window * (1 - ((capacity - requested) / currentCnt)))
+			end
+
+			-- the rule that keeps the request out the longest is the binding one
+			if allowed == 1 or retry_after > binding_retry then
+				binding = i
+				binding_retry = retry_after
+				binding_reset = reset_after(currentCnt, previousCnt, elapsed, window)
+				binding_remaining = math.max(0, capacity - effectiveCnt)
+			end
+			allowed = 0
+		elseif allowed == 1 then
+			local remaining = capacity - effectiveCnt - requested
+			if binding_remaining < 0 or remaining < binding_remaining then
+				binding = i
+				-- the request is consumed from the rule once every rule allowed it
+				binding_reset = reset_after(currentCnt + requested, previousCnt, elapsed, window)
+				binding_remaining = remaining
+			end
+		end
+	end
+
+	-- Consume from every rule only when all of them allow the request
+	if allowed == 1 then
+		for i = 1, #KEYS do
+			local state = states[i]
+			redis.call("HMSET", KEYS[i], "currentCnt", state[1] + requested, "previousCnt", state[2], "windowStart", state[3])
+			redis.call("PEXPIRE", KEYS[i], 2 * state[4])
+		end
+	end
+
+	return {allowed, binding, math.floor(binding_remaining), math.ceil(math.max(binding_retry, 0)), math.ceil(binding_reset)}
+	`
+
+	return script
+}
@@ -20,7 +20,7 @@ func GetSlidingWindowScript() string {
 
 	-- Shift windows if the current window has passed
 	if ellapsed >= window then
-		local shift = math.ceil(ellapsed / window)
+		local shift = math.floor(ellapsed / window)
 
 		if shift >= 2 then
 			previousCnt = 0
@@ -38,9 +38,28 @@ func GetSlidingWindowScript() string {
 	local effectiveCnt = currentCnt + ( previousCnt * weight )
 
 	local allowed = 0
+	local retry_after = 0
 	if effectiveCnt + requested <= capacity then
 		allowed = 1
-		currentCnt = effectiveCnt + requested
+		currentCnt = currentCnt + requested
+		effectiveCnt = effectiveCnt + requested
+	elseif requested > capacity then
+		-- the request can never fit, ask to come back after a full window
+		retry_after = window
+	elseif currentCnt + requested <= capacity then
+		-- enough of the previous window has to fade out
+		retry_after = (window * (1 - ((capacity - currentCnt - requested) / previousCnt))) - ellapsed
+	else
+		-- the current window has to become the previous one and fade out in turn
+		retry_after = (window - ellapsed) + (window * (1 - ((capacity - requested) / currentCnt)))
+	end
+
+	-- the whole limit is available once every counted request has faded out
+	local reset = 0
+	if currentCnt > 0 then
+		reset = (2 * window) - ellapsed
+	elseif previousCnt > 0 then
+		reset = window - ellapsed
 	end
 
 	-- Store the data
@@ -51,7 +70,52 @@ func GetSlidingWindowScript() string {
 
 	redis.call("PEXPIRE", key, ttl)
 
-	return allowed
+	return {allowed, math.floor(math.max(0, capacity - effectiveCnt)), math.ceil(math.max(retry_after, 0)), math.ceil(reset)}
+	`
+
+	return script
+}
+
+// GetSlidingWindowRefundScript takes the cost back from the counters of every key, the arguments are laid out like
+// those of the multi window script so that single and multi window limiters share it
+func GetSlidingWindowRefundScript() string {
+	script := `
+	local now = tonumber(ARGV[1])
+	local refunded = tonumber(ARGV[2])
+
+	for i = 1, #KEYS do
+		local window = tonumber(ARGV[2 + (i * 2)])
+
+		local result = redis.call("HMGET", KEYS[i], "currentCnt", "previousCnt", "windowStart")
+		local currentCnt = tonumber(result[1])
+		local previousCnt = tonumber(result[2]) or 0
+		local windowStart = tonumber(result[3])
+
+		if currentCnt ~= nil and windowStart ~= nil then
+			local elapsed = now - windowStart
+			if elapsed >= window then
+				local shift = math.floor(elapsed / window)
+
+				if shift >= 2 then
+					previousCnt = 0
+				else
+					previousCnt = currentCnt
+				end
+
+				currentCnt = 0
+				windowStart = windowStart + (shift * window)
+			end
+
+			-- the cost was counted in the current window, unless that has become the previous one since
+			local fromCurrent = math.min(currentCnt, refunded)
+			currentCnt = currentCnt - fromCurrent
+			previousCnt = math.max(0, previousCnt - (refunded - fromCurrent))
+
+			redis.call("HMSET", KEYS[i], "currentCnt", currentCnt, "previousCnt", previousCnt, "windowStart", windowStart)
+		end
+	end
+
+	return 1
 	`
 
 	return script
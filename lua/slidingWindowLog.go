@@ -0,0 +1,64 @@
+package lua
+
+func GetSlidingWindowLogScript() string {
+	script := `
+	local key = KEYS[1]
+
+	local capacity = tonumber(ARGV[1])
+	local window = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local requested = tonumber(ARGV[4])
+	local member = ARGV[5]
+
+	-- Drop the requests that left the window
+	redis.call("ZREMRANGEBYSCORE", key, "-inf", now - window)
+	local count = redis.call("ZCARD", key)
+
+	local allowed = 0
+	local retry_after = 0
+
+	if count + requested <= capacity then
+		for i = 1, requested do
+			redis.call("ZADD", key, now, member .. ":" .. i)
+		end
+		count = count + requested
+		allowed = 1
+	elseif requested > capacity then
+		-- the request can never fit, ask to come back after a full window
+		retry_after = window
+	else
+		-- wait until enough of the oldest requests have left the window
+		local oldest = redis.call("ZRANGE", key, count + requested - capacity - 1, count + requested - capacity - 1, "WITHSCORES")
+		retry_after = tonumber(oldest[2]) + window - now
+	end
+
+	-- the whole limit is available again once the newest request has left the window
+	local reset = 0
+	local newest = redis.call("ZRANGE", key, -1, -1, "WITHSCORES")
+	if newest[2] then
+		reset = tonumber(newest[2]) + window - now
+	end
+
+	-- SET TTL
+	redis.call("PEXPIRE", key, window)
+
+	return {allowed, capacity - count, retry_after, reset}
+	`
+
+	return script
+}
+
+func GetSlidingWindowLogRefundScript() string {
+	script := `
+	local key = KEYS[1]
+
+	local refunded = tonumber(ARGV[1])
+
+	-- the refunded request is among the newest ones logged, any of them frees the same room
+	redis.call("ZPOPMAX", key, refunded)
+
+	return 1
+	`
+
+	return script
+}
@@ -25,12 +25,18 @@ func GetTokenBucketScript() string {
 	tokens = math.min(tokens, capacity)
 
 	local allowed = 0
+	local retry_after = 0
 
 	if tokens >= requested then
 		tokens = tokens - requested
 		allowed = 1
+	else
+		-- a request larger than the bucket can never fit, it is asked to come back once the bucket is full
+		retry_after = (math.min(requested, capacity) - tokens) / refill_rate
 	end
 
+	local reset = (capacity - tokens) / refill_rate
+
 	-- Save state
 	redis.call("HMSET", key, "tokens", tokens, "last_refill", now)
 
@@ -38,8 +44,30 @@ func GetTokenBucketScript() string {
 	local ttl = math.ceil((capacity / refill_rate) * 2)
 	redis.call("EXPIRE", key, ttl)
 
-	return {allowed, tokens}
+	-- the waits are returned in milliseconds, redis truncates lua numbers to integers
+	return {allowed, math.floor(tokens), math.ceil(retry_after * 1000), math.ceil(reset * 1000)}
 	`
 
 	return tokenScript
 }
+
+func GetTokenBucketRefundScript() string {
+	script := `
+	local key = KEYS[1]
+
+	local capacity = tonumber(ARGV[1])
+	local refunded = tonumber(ARGV[2])
+
+	local tokens = tonumber(redis.call("HGET", key, "tokens"))
+	if tokens == nil then
+		return 0
+	end
+
+	-- the next refill is capped at the capacity again, so the tokens handed back never overflow the bucket
+	redis.call("HSET", key, "tokens", math.min(capacity, tokens + refunded))
+
+	return 1
+	`
+
+	return script
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"goapp/cli"
 	"goapp/metrics"
 	"goapp/server"
+	"os"
 
 	"github.com/gofiber/fiber/v2/log"
 )
 
 func main() {
+	// policy management runs as a subcommand instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == cli.CommandPolicies {
+		os.Exit(cli.RunPolicies(os.Args[2:]))
+	}
+
 	metrics.InitMetrics()
 
 	filePath := "deploy/config.json"
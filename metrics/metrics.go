@@ -26,6 +26,38 @@ var (
 		},
 	)
 
+	TrackedKeys = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rate_limiter_memory_tracked_keys",
+			Help: "Number of keys currently held by the in-memory limiters",
+		},
+		[]string{"algorithm"},
+	)
+
+	EvictedKeys = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limiter_memory_evicted_keys_total",
+			Help: "Total keys dropped from the in-memory limiters",
+		},
+		[]string{"algorithm", "reason"},
+	)
+
+	DegradedDecisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limiter_degraded_decisions_total",
+			Help: "Total decisions taken by the policy failure mode while the limiter backend was unavailable",
+		},
+		[]string{"algorithm", "mode", "reason"},
+	)
+
+	ShadowDenials = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limiter_shadow_denials_total",
+			Help: "Total requests a policy in shadow mode would have rejected",
+		},
+		[]string{"policy", "algorithm"},
+	)
+
 	RedisLatency = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "rate_limiter_redis_latency_seconds",
@@ -41,5 +73,9 @@ func InitMetrics() {
 		RequestsLatency,
 		RedisErrors,
 		RedisLatency,
+		TrackedKeys,
+		EvictedKeys,
+		DegradedDecisions,
+		ShadowDenials,
 	)
 }
@@ -2,11 +2,95 @@ package models
 
 type Ports struct {
 	FiberServer string `json:"fiberServer"`
+	GrpcServer  string `json:"grpcServer"`
 }
 
+type MemoryStore struct {
+	MaxKeys         int64  `json:"maxKeys"`
+	EvictionSamples int    `json:"evictionSamples"`
+	SweepInterval   string `json:"sweepInterval"`
+}
+
+// PolicyStoreConfig selects where policies are read from, a file store reloads the file when it changes
+type PolicyStoreConfig struct {
+	Type         string `json:"type"`
+	File         string `json:"file"`
+	PollInterval string `json:"pollInterval"`
+}
+
+// PolicyNotifications is the channel the rateLimitPolicies trigger notifies on, setup creates the trigger
+type PolicyNotifications struct {
+	Channel string   `json:"channel"`
+	Setup   []string `json:"setup"`
+}
+
+// ForwardAuth configures the forward-auth endpoint, the first rule the forwarded request has a value for decides
+// the scope and identifier it is limited under. The client ip is read from the header the proxies append to, skipping
+// the entries added by the trusted proxies, and the failure mode decides the requests the limiter fails on
+type ForwardAuth struct {
+	Type           string            `json:"type"`
+	Rules          []ForwardAuthRule `json:"rules"`
+	ClientIpHeader string            `json:"clientIpHeader,omitempty"`
+	TrustedProxies int               `json:"trustedProxies"`
+	FailureMode    string            `json:"failureMode,omitempty"`
+}
+
+// ForwardAuthRule takes the identifier from a header, the path or the client ip of the original request, an optional
+// pattern narrows it down to its first capture group
+type ForwardAuthRule struct {
+	Scope   string `json:"scope"`
+	Source  string `json:"source"`
+	Header  string `json:"header,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+type Fallback struct {
+	Enabled           bool `json:"enabled"`
+	ExpectedInstances int  `json:"expectedInstances"`
+}
+
+// LimiterResponse is the decision on a request, retryAfter and reset are in seconds from now, reset being the time
+// until the whole limit is available again and window the period the limit applies to
 type LimiterResponse struct {
-	Allowed         bool  `json:"allowed"`
-	RetryAfter      int64 `json:"retryAfter"`
-	RemainingTokens int64 `json:"remaining"`
-	TotalTokens     int64 `json:"limt"`
+	Allowed         bool   `json:"allowed"`
+	RetryAfter      int64  `json:"retryAfter"`
+	RemainingTokens int64  `json:"remaining"`
+	TotalTokens     int64  `json:"limt"`
+	Reset           int64  `json:"reset"`
+	Window          int64  `json:"window,omitempty"`
+	Degraded        bool   `json:"degraded,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+	LeaseId         string `json:"leaseId,omitempty"`
+	Rule            string `json:"rule,omitempty"`
+	Level           string `json:"level,omitempty"`
+	PolicyVersion   int64  `json:"policyVersion,omitempty"`
+	ShadowDenied    bool   `json:"shadowDenied,omitempty"`
+	Schedule        string `json:"schedule,omitempty"`
+}
+
+// BatchItem is one limit checked by a batch request, a cost of zero means the default cost
+type BatchItem struct {
+	Scope      string `json:"scope"`
+	Identifier string `json:"identifier"`
+	Type       string `json:"type"`
+	Cost       int64  `json:"cost"`
+}
+
+type BatchRequest struct {
+	Items        []BatchItem `json:"items"`
+	AllOrNothing bool        `json:"allOrNothing"`
+}
+
+// BatchResult is the decision for one item, or why no decision could be made for it, see logic.BatchResult
+type BatchResult struct {
+	*LimiterResponse
+	Charged    bool   `json:"charged"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	RejectedBy *int   `json:"rejectedBy,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Allowed bool          `json:"allowed"`
+	Results []BatchResult `json:"results"`
 }
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"goapp/constants"
+	"strings"
+)
+
+// PolicyFile is the layout of the files policies are exported to, synced from and served by the file policy store
+type PolicyFile struct {
+	Policies []*PolicySchema `json:"policies"`
+}
+
+// Wildcard stands for any run of characters in the scope or identifier of a pattern policy
+const Wildcard = "*"
+
+type LimitRule struct {
+	Name   string `json:"name"`
+	Limit  int    `json:"limit"`
+	Window string `json:"window"`
+}
+
+type PolicySchema struct {
+	Scope       string      `json:"scope"`
+	Identifier  string      `json:"identifier"`
+	Limit       int         `json:"limit"`
+	Window      string      `json:"window"`
+	Burst       int         `json:"burst"`
+	Algorithm   string      `json:"algorithm"`
+	FailureMode string      `json:"failureMode,omitempty"`
+	Mode        string      `json:"mode,omitempty"`
+	Rules       []LimitRule `json:"rules,omitempty"`
+
+	// Schedules override the limits above while they are active, see ActiveAt
+	Schedules      []PolicySchedule `json:"schedules,omitempty"`
+	ActiveSchedule string           `json:"-"`
+
+	// Version is set when the policy is written through the api, it counts every change of the key
+	Version int64 `json:"version,omitempty"`
+}
+
+// Key is the key the policy is stored and cached under
+func (p *PolicySchema) Key() string {
+	return p.Scope + ":" + p.Identifier
+}
+
+// EffectiveAlgorithm is the algorithm the policy is evaluated with, policies carrying rules always use the multi window limiter
+func (p *PolicySchema) EffectiveAlgorithm() string {
+	if len(p.Rules) > 0 {
+		return constants.AlgorithmMultiWindow
+	}
+	return p.Algorithm
+}
+
+// IsPattern tells whether the policy applies to a family of keys rather than a single one
+func (p *PolicySchema) IsPattern() bool {
+	return strings.Contains(p.Scope, Wildcard) || strings.Contains(p.Identifier, Wildcard)
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	// schedules name their time zone, the database is embedded for hosts without one
+	_ "time/tzdata"
+)
+
+// PolicySchedule overrides the limits of a policy while it is active, an empty field keeps the value of the policy.
+// A schedule is active between Start and End when set, on the given Days and between the From and To time of day,
+// all evaluated in Timezone (UTC when empty). Schedules are parsed when they are loaded or validated, one built in
+// code is parsed the first time it is evaluated
+type PolicySchedule struct {
+	Name     string   `json:"name"`
+	Timezone string   `json:"timezone,omitempty"`
+	Start    string   `json:"start,omitempty"`
+	End      string   `json:"end,omitempty"`
+	Days     []string `json:"days,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       string   `json:"to,omitempty"`
+
+	Limit     int         `json:"limit,omitempty"`
+	Window    string      `json:"window,omitempty"`
+	Burst     int         `json:"burst,omitempty"`
+	Algorithm string      `json:"algorithm,omitempty"`
+	Rules     []LimitRule `json:"rules,omitempty"`
+
+	// bounds is set once, by whichever of loading, validating or evaluating the schedule parses it first
+	bounds atomic.Pointer[scheduleBounds]
+}
+
+const clockLayout = "15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// scheduleBounds is the schedule parsed once, so that checking it only compares values
+type scheduleBounds struct {
+	invalid    bool
+	location   *time.Location
+	start      time.Time
+	end        time.Time
+	days       [7]bool
+	anyDay     bool
+	fromMinute int
+	toMinute   int
+	allDay     bool
+}
+
+// UnmarshalJSON parses the schedule as it is loaded, a schedule that does not validate never applies
+func (s *PolicySchedule) UnmarshalJSON(data []byte) error {
+	type plainSchedule PolicySchedule
+	if err := sonic.Unmarshal(data, (*plainSchedule)(s)); err != nil {
+		return err
+	}
+
+	s.bounds.Store(s.parseOrInvalid())
+	return nil
+}
+
+// Validate checks that the schedule can be evaluated and keeps it parsed for evaluating it
+func (s *PolicySchedule) Validate() error {
+	bounds, err := s.parse()
+	if err != nil {
+		return err
+	}
+
+	s.bounds.Store(bounds)
+	return nil
+}
+
+// parseOrInvalid parses the schedule, a schedule that does not validate is kept as invalid so it is not parsed again
+func (s *PolicySchedule) parseOrInvalid() *scheduleBounds {
+	bounds, err := s.parse()
+	if err != nil {
+		return &scheduleBounds{invalid: true}
+	}
+	return bounds
+}
+
+// parsedBounds returns the parsed schedule, parsing it on first use when it was neither loaded nor validated
+func (s *PolicySchedule) parsedBounds() *scheduleBounds {
+	if bounds := s.bounds.Load(); bounds != nil {
+		return bounds
+	}
+
+	s.bounds.CompareAndSwap(nil, s.parseOrInvalid())
+	return s.bounds.Load()
+}
+
+func (s *PolicySchedule) parse() (*scheduleBounds, error) {
+	if s.Name == "" {
+		return nil, fmt.Errorf("schedules need a name")
+	}
+
+	bounds := &scheduleBounds{anyDay: len(s.Days) == 0, allDay: s.From == ""}
+
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s : invalid timezone %q", s.Name, s.Timezone)
+	}
+	bounds.location = location
+
+	for _, bound := range []struct {
+		value  string
+		parsed *time.Time
+	}{{s.Start, &bounds.start}, {s.End, &bounds.end}} {
+		if bound.value == "" {
+			continue
+		}
+		if *bound.parsed, err = time.Parse(time.RFC3339, bound.value); err != nil {
+			return nil, fmt.Errorf("schedule %s : %q is not an RFC 3339 timestamp", s.Name, bound.value)
+		}
+	}
+
+	for _, day := range s.Days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("schedule %s : unknown day %q", s.Name, day)
+		}
+		bounds.days[weekday] = true
+	}
+
+	if (s.From == "") != (s.To == "") {
+		return nil, fmt.Errorf("schedule %s : from and to go together", s.Name)
+	}
+	for _, clock := range []struct {
+		value  string
+		minute *int
+	}{{s.From, &bounds.fromMinute}, {s.To, &bounds.toMinute}} {
+		if clock.value == "" {
+			continue
+		}
+		parsed, err := time.Parse(clockLayout, clock.value)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s : %q is not a HH:MM time", s.Name, clock.value)
+		}
+		*clock.minute = parsed.Hour()*60 + parsed.Minute()
+	}
+
+	return bounds, nil
+}
+
+// activeAt tells whether the schedule applies at the given instant, schedules that do not validate never apply
+func (s *PolicySchedule) activeAt(now time.Time) bool {
+	bounds := s.parsedBounds()
+	if bounds.invalid {
+		return false
+	}
+	now = now.In(bounds.location)
+
+	if !bounds.start.IsZero() && now.Before(bounds.start) {
+		return false
+	}
+	if !bounds.end.IsZero() && !now.Before(bounds.end) {
+		return false
+	}
+	if !bounds.anyDay && !bounds.days[now.Weekday()] {
+		return false
+	}
+	if bounds.allDay {
+		return true
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+
+	// windows like 22:00 to 06:00 run over midnight
+	if bounds.fromMinute <= bounds.toMinute {
+		return minute >= bounds.fromMinute && minute < bounds.toMinute
+	}
+	return minute >= bounds.fromMinute || minute < bounds.toMinute
+}
+
+// ActiveAt returns the policy as it applies at the given instant, the first active schedule overrides its limits
+func (p *PolicySchema) ActiveAt(now time.Time) *PolicySchema {
+	for i := range p.Schedules {
+		if p.Schedules[i].activeAt(now) {
+			return p.WithSchedule(&p.Schedules[i])
+		}
+	}
+
+	return p
+}
+
+// WithSchedule returns a copy of the policy with the limits of the schedule applied
+func (p *PolicySchema) WithSchedule(schedule *PolicySchedule) *PolicySchema {
+	active := *p
+	active.ActiveSchedule = schedule.Name
+	if schedule.Limit != 0 {
+		active.Limit = schedule.Limit
+	}
+	if schedule.Window != "" {
+		active.Window = schedule.Window
+	}
+	if schedule.Burst != 0 {
+		active.Burst = schedule.Burst
+	}
+	if schedule.Algorithm != "" {
+		active.Algorithm = schedule.Algorithm
+	}
+	if len(schedule.Rules) > 0 {
+		active.Rules = schedule.Rules
+	}
+	return &active
+}
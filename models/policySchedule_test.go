@@ -0,0 +1,104 @@
+package models_test
+
+import (
+	"goapp/models"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+const scheduledPolicy = `{
+	"scope": "api", "identifier": "*", "algorithm": "fixed_window", "limit": 100, "window": "1m",
+	"schedules": [
+		{ "name": "black-friday", "start": "2026-11-27T00:00:00-05:00", "end": "2026-11-28T00:00:00-05:00", "limit": 1000 },
+		{ "name": "maintenance", "timezone": "Europe/Berlin", "days": ["sun"], "from": "02:00", "to": "04:00", "limit": 5 },
+		{ "name": "business-hours", "timezone": "America/New_York", "days": ["mon", "tue", "wed", "thu", "fri"], "from": "09:00", "to": "17:00", "limit": 300 },
+		{ "name": "nightly", "from": "22:00", "to": "06:00", "limit": 50 },
+		{ "name": "broken", "timezone": "Mars/Olympus_Mons", "limit": 1 }
+	]
+}`
+
+// builtPolicy is scheduledPolicy built in code, its schedules are neither loaded nor validated before they are evaluated
+func builtPolicy() *models.PolicySchema {
+	return &models.PolicySchema{
+		Scope: "api", Identifier: "*", Algorithm: "fixed_window", Limit: 100, Window: "1m",
+		Schedules: []models.PolicySchedule{
+			{Name: "black-friday", Start: "2026-11-27T00:00:00-05:00", End: "2026-11-28T00:00:00-05:00", Limit: 1000},
+			{Name: "maintenance", Timezone: "Europe/Berlin", Days: []string{"sun"}, From: "02:00", To: "04:00", Limit: 5},
+			{Name: "business-hours", Timezone: "America/New_York", Days: []string{"mon", "tue", "wed", "thu", "fri"}, From: "09:00", To: "17:00", Limit: 300},
+			{Name: "nightly", From: "22:00", To: "06:00", Limit: 50},
+			{Name: "broken", Timezone: "Mars/Olympus_Mons", Limit: 1},
+		},
+	}
+}
+
+func TestPolicyActiveAt(t *testing.T) {
+	loaded := &models.PolicySchema{}
+	if err := sonic.Unmarshal([]byte(scheduledPolicy), loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		at       string
+		schedule string
+		limit    int
+	}{
+		{name: "before the sale", at: "2026-11-26T21:00:00Z", schedule: "business-hours", limit: 300},
+		{name: "sale starts at midnight in its own offset", at: "2026-11-27T05:00:00Z", schedule: "black-friday", limit: 1000},
+		{name: "first active schedule wins", at: "2026-11-27T15:00:00Z", schedule: "black-friday", limit: 1000},
+		{name: "after the sale", at: "2026-11-28T12:00:00Z", schedule: "", limit: 100},
+		{name: "sunday night in berlin", at: "2026-03-01T01:30:00Z", schedule: "maintenance", limit: 5},
+		{name: "later on sunday in berlin", at: "2026-03-01T10:00:00Z", schedule: "", limit: 100},
+		{name: "friday afternoon in new york", at: "2026-03-06T21:30:00Z", schedule: "business-hours", limit: 300},
+		{name: "friday evening in new york", at: "2026-03-06T22:30:00Z", schedule: "nightly", limit: 50},
+		{name: "saturday in new york", at: "2026-03-07T15:00:00Z", schedule: "", limit: 100},
+		{name: "before midnight", at: "2026-03-07T23:00:00Z", schedule: "nightly", limit: 50},
+		{name: "after midnight", at: "2026-03-08T05:59:00Z", schedule: "nightly", limit: 50},
+		{name: "end of the night", at: "2026-03-08T06:00:00Z", schedule: "", limit: 100},
+	}
+
+	for _, source := range []struct {
+		name   string
+		policy *models.PolicySchema
+	}{{"loaded", loaded}, {"built in code", builtPolicy()}} {
+		for _, tt := range tests {
+			t.Run(source.name+"/"+tt.name, func(t *testing.T) {
+				at, err := time.Parse(time.RFC3339, tt.at)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				active := source.policy.ActiveAt(at)
+				if active.ActiveSchedule != tt.schedule || active.Limit != tt.limit {
+					t.Fatalf("expected schedule %q with a limit of %d, got %q with %d", tt.schedule, tt.limit, active.ActiveSchedule, active.Limit)
+				}
+			})
+		}
+	}
+}
+
+func TestPolicyScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *models.PolicySchedule
+		valid    bool
+	}{
+		{name: "time of day", schedule: &models.PolicySchedule{Name: "night", From: "22:00", To: "06:00"}, valid: true},
+		{name: "without a name", schedule: &models.PolicySchedule{From: "22:00", To: "06:00"}},
+		{name: "unknown timezone", schedule: &models.PolicySchedule{Name: "night", Timezone: "Mars/Olympus_Mons"}},
+		{name: "unknown day", schedule: &models.PolicySchedule{Name: "night", Days: []string{"someday"}}},
+		{name: "from without to", schedule: &models.PolicySchedule{Name: "night", From: "22:00"}},
+		{name: "invalid time of day", schedule: &models.PolicySchedule{Name: "night", From: "10pm", To: "06:00"}},
+		{name: "invalid start", schedule: &models.PolicySchedule{Name: "sale", Start: "2026-11-27"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.schedule.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
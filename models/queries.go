@@ -1,10 +1,35 @@
 package models
 
 type Queries struct {
-	Fetch Fetch `json:"fetch"`
+	Fetch   Fetch   `json:"fetch"`
+	Modify  Modify  `json:"modify"`
+	History History `json:"history"`
 }
 
 type Fetch struct {
 	FetchPolicies    string `json:"fetchPolicies"`
 	FetchPolicyByKey string `json:"fetchPolicyByKey"`
+
+	// pattern policies carry a * in their key
+	FetchPatternPolicies string `json:"fetchPatternPolicies"`
+
+	// records carry the updatedAt column next to the policy
+	FetchPolicyRecords string `json:"fetchPolicyRecords"`
+	FetchPolicyRecord  string `json:"fetchPolicyRecord"`
+}
+
+type Modify struct {
+	InsertPolicy string `json:"insertPolicy"`
+	UpdatePolicy string `json:"updatePolicy"`
+	DeletePolicy string `json:"deletePolicy"`
+	UpsertPolicy string `json:"upsertPolicy"`
+	LockPolicy   string `json:"lockPolicy"`
+	LockPolicies string `json:"lockPolicies"`
+}
+
+type History struct {
+	NextPolicyVersion   string `json:"nextPolicyVersion"`
+	InsertPolicyVersion string `json:"insertPolicyVersion"`
+	FetchPolicyVersions string `json:"fetchPolicyVersions"`
+	FetchPolicyVersion  string `json:"fetchPolicyVersion"`
 }
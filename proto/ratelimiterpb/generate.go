@@ -0,0 +1,3 @@
+package ratelimiterpb
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=module=goapp --go-grpc_out=../.. --go-grpc_opt=module=goapp proto/ratelimiter.proto
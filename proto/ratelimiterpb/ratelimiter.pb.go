@@ -0,0 +1,592 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: proto/ratelimiter.proto
+
+package ratelimiterpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CheckRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Scope      string                 `protobuf:"bytes,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	Identifier string                 `protobuf:"bytes,2,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	Type       string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	// units consumed by the request, 0 means 1
+	Cost int64 `protobuf:"varint,4,opt,name=cost,proto3" json:"cost,omitempty"`
+	// levels of the hierarchy, only used when scope and identifier are empty
+	Tenant        string `protobuf:"bytes,5,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	User          string `protobuf:"bytes,6,opt,name=user,proto3" json:"user,omitempty"`
+	Route         string `protobuf:"bytes,7,opt,name=route,proto3" json:"route,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckRequest) Reset() {
+	*x = CheckRequest{}
+	mi := &file_proto_ratelimiter_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckRequest) ProtoMessage() {}
+
+func (x *CheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ratelimiter_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckRequest.ProtoReflect.Descriptor instead.
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_ratelimiter_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CheckRequest) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetIdentifier() string {
+	if x != nil {
+		return x.Identifier
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetCost() int64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+func (x *CheckRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetRoute() string {
+	if x != nil {
+		return x.Route
+	}
+	return ""
+}
+
+type CheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	RetryAfter    int64                  `protobuf:"varint,2,opt,name=retry_after,json=retryAfter,proto3" json:"retry_after,omitempty"`
+	Remaining     int64                  `protobuf:"varint,3,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	Limit         int64                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	Degraded      bool                   `protobuf:"varint,5,opt,name=degraded,proto3" json:"degraded,omitempty"`
+	Reason        string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	LeaseId       string                 `protobuf:"bytes,7,opt,name=lease_id,json=leaseId,proto3" json:"lease_id,omitempty"`
+	Rule          string                 `protobuf:"bytes,8,opt,name=rule,proto3" json:"rule,omitempty"`
+	Level         string                 `protobuf:"bytes,9,opt,name=level,proto3" json:"level,omitempty"`
+	PolicyVersion int64                  `protobuf:"varint,10,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`
+	ShadowDenied  bool                   `protobuf:"varint,11,opt,name=shadow_denied,json=shadowDenied,proto3" json:"shadow_denied,omitempty"`
+	Schedule      string                 `protobuf:"bytes,12,opt,name=schedule,proto3" json:"schedule,omitempty"`
+	Error         string                 `protobuf:"bytes,13,opt,name=error,proto3" json:"error,omitempty"`
+	// seconds until the whole limit is available again
+	ResetAfter int64 `protobuf:"varint,14,opt,name=reset_after,json=resetAfter,proto3" json:"reset_after,omitempty"`
+	// seconds the limit applies to
+	Window int64 `protobuf:"varint,15,opt,name=window,proto3" json:"window,omitempty"`
+	// batch results only, whether the cost stays charged to the item
+	Charged bool `protobuf:"varint,16,opt,name=charged,proto3" json:"charged,omitempty"`
+	// batch results only, the item was not checked because an earlier item rejected the all-or-nothing batch
+	Skipped bool `protobuf:"varint,17,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	// batch results only, the index of the item that rejected the all-or-nothing batch
+	RejectedBy    *int32 `protobuf:"varint,18,opt,name=rejected_by,json=rejectedBy,proto3,oneof" json:"rejected_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckResponse) Reset() {
+	*x = CheckResponse{}
+	mi := &file_proto_ratelimiter_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResponse) ProtoMessage() {}
+
+func (x *CheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ratelimiter_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResponse.ProtoReflect.Descriptor instead.
+func (*CheckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_ratelimiter_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CheckResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *CheckResponse) GetRetryAfter() int64 {
+	if x != nil {
+		return x.RetryAfter
+	}
+	return 0
+}
+
+func (x *CheckResponse) GetRemaining() int64 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *CheckResponse) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *CheckResponse) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+func (x *CheckResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetLeaseId() string {
+	if x != nil {
+		return x.LeaseId
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetPolicyVersion() int64 {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return 0
+}
+
+func (x *CheckResponse) GetShadowDenied() bool {
+	if x != nil {
+		return x.ShadowDenied
+	}
+	return false
+}
+
+func (x *CheckResponse) GetSchedule() string {
+	if x != nil {
+		return x.Schedule
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetResetAfter() int64 {
+	if x != nil {
+		return x.ResetAfter
+	}
+	return 0
+}
+
+func (x *CheckResponse) GetWindow() int64 {
+	if x != nil {
+		return x.Window
+	}
+	return 0
+}
+
+func (x *CheckResponse) GetCharged() bool {
+	if x != nil {
+		return x.Charged
+	}
+	return false
+}
+
+func (x *CheckResponse) GetSkipped() bool {
+	if x != nil {
+		return x.Skipped
+	}
+	return false
+}
+
+func (x *CheckResponse) GetRejectedBy() int32 {
+	if x != nil && x.RejectedBy != nil {
+		return *x.RejectedBy
+	}
+	return 0
+}
+
+type BatchItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scope         string                 `protobuf:"bytes,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	Identifier    string                 `protobuf:"bytes,2,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Cost          int64                  `protobuf:"varint,4,opt,name=cost,proto3" json:"cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	mi := &file_proto_ratelimiter_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ratelimiter_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_proto_ratelimiter_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchItem) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *BatchItem) GetIdentifier() string {
+	if x != nil {
+		return x.Identifier
+	}
+	return ""
+}
+
+func (x *BatchItem) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *BatchItem) GetCost() int64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+type BatchCheckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*BatchItem           `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	AllOrNothing  bool                   `protobuf:"varint,2,opt,name=all_or_nothing,json=allOrNothing,proto3" json:"all_or_nothing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCheckRequest) Reset() {
+	*x = BatchCheckRequest{}
+	mi := &file_proto_ratelimiter_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCheckRequest) ProtoMessage() {}
+
+func (x *BatchCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ratelimiter_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCheckRequest.ProtoReflect.Descriptor instead.
+func (*BatchCheckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_ratelimiter_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchCheckRequest) GetItems() []*BatchItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *BatchCheckRequest) GetAllOrNothing() bool {
+	if x != nil {
+		return x.AllOrNothing
+	}
+	return false
+}
+
+type BatchCheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Results       []*CheckResponse       `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCheckResponse) Reset() {
+	*x = BatchCheckResponse{}
+	mi := &file_proto_ratelimiter_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCheckResponse) ProtoMessage() {}
+
+func (x *BatchCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ratelimiter_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCheckResponse.ProtoReflect.Descriptor instead.
+func (*BatchCheckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_ratelimiter_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchCheckResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *BatchCheckResponse) GetResults() []*CheckResponse {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_proto_ratelimiter_proto protoreflect.FileDescriptor
+
+const file_proto_ratelimiter_proto_rawDesc = "" +
+	"\n" +
+	"\x17proto/ratelimiter.proto\x12\x0eratelimiter.v1\"\xae\x01\n" +
+	"\fCheckRequest\x12\x14\n" +
+	"\x05scope\x18\x01 \x01(\tR\x05scope\x12\x1e\n" +
+	"\n" +
+	"identifier\x18\x02 \x01(\tR\n" +
+	"identifier\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x12\n" +
+	"\x04cost\x18\x04 \x01(\x03R\x04cost\x12\x16\n" +
+	"\x06tenant\x18\x05 \x01(\tR\x06tenant\x12\x12\n" +
+	"\x04user\x18\x06 \x01(\tR\x04user\x12\x14\n" +
+	"\x05route\x18\a \x01(\tR\x05route\"\x98\x04\n" +
+	"\rCheckResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x1f\n" +
+	"\vretry_after\x18\x02 \x01(\x03R\n" +
+	"retryAfter\x12\x1c\n" +
+	"\tremaining\x18\x03 \x01(\x03R\tremaining\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x03R\x05limit\x12\x1a\n" +
+	"\bdegraded\x18\x05 \x01(\bR\bdegraded\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x12\x19\n" +
+	"\blease_id\x18\a \x01(\tR\aleaseId\x12\x12\n" +
+	"\x04rule\x18\b \x01(\tR\x04rule\x12\x14\n" +
+	"\x05level\x18\t \x01(\tR\x05level\x12%\n" +
+	"\x0epolicy_version\x18\n" +
+	" \x01(\x03R\rpolicyVersion\x12#\n" +
+	"\rshadow_denied\x18\v \x01(\bR\fshadowDenied\x12\x1a\n" +
+	"\bschedule\x18\f \x01(\tR\bschedule\x12\x14\n" +
+	"\x05error\x18\r \x01(\tR\x05error\x12\x1f\n" +
+	"\vreset_after\x18\x0e \x01(\x03R\n" +
+	"resetAfter\x12\x16\n" +
+	"\x06window\x18\x0f \x01(\x03R\x06window\x12\x18\n" +
+	"\acharged\x18\x10 \x01(\bR\acharged\x12\x18\n" +
+	"\askipped\x18\x11 \x01(\bR\askipped\x12$\n" +
+	"\vrejected_by\x18\x12 \x01(\x05H\x00R\n" +
+	"rejectedBy\x88\x01\x01B\x0e\n" +
+	"\f_rejected_by\"i\n" +
+	"\tBatchItem\x12\x14\n" +
+	"\x05scope\x18\x01 \x01(\tR\x05scope\x12\x1e\n" +
+	"\n" +
+	"identifier\x18\x02 \x01(\tR\n" +
+	"identifier\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x12\n" +
+	"\x04cost\x18\x04 \x01(\x03R\x04cost\"j\n" +
+	"\x11BatchCheckRequest\x12/\n" +
+	"\x05items\x18\x01 \x03(\v2\x19.ratelimiter.v1.BatchItemR\x05items\x12$\n" +
+	"\x0eall_or_nothing\x18\x02 \x01(\bR\fallOrNothing\"g\n" +
+	"\x12BatchCheckResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x127\n" +
+	"\aresults\x18\x02 \x03(\v2\x1d.ratelimiter.v1.CheckResponseR\aresults2\xf8\x01\n" +
+	"\vRateLimiter\x12D\n" +
+	"\x05Check\x12\x1c.ratelimiter.v1.CheckRequest\x1a\x1d.ratelimiter.v1.CheckResponse\x12S\n" +
+	"\n" +
+	"BatchCheck\x12!.ratelimiter.v1.BatchCheckRequest\x1a\".ratelimiter.v1.BatchCheckResponse\x12N\n" +
+	"\vStreamCheck\x12\x1c.ratelimiter.v1.CheckRequest\x1a\x1d.ratelimiter.v1.CheckResponse(\x010\x01B\x1bZ\x19goapp/proto/ratelimiterpbb\x06proto3"
+
+var (
+	file_proto_ratelimiter_proto_rawDescOnce sync.Once
+	file_proto_ratelimiter_proto_rawDescData []byte
+)
+
+func file_proto_ratelimiter_proto_rawDescGZIP() []byte {
+	file_proto_ratelimiter_proto_rawDescOnce.Do(func() {
+		file_proto_ratelimiter_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_ratelimiter_proto_rawDesc), len(file_proto_ratelimiter_proto_rawDesc)))
+	})
+	return file_proto_ratelimiter_proto_rawDescData
+}
+
+var file_proto_ratelimiter_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_ratelimiter_proto_goTypes = []any{
+	(*CheckRequest)(nil),       // 0: ratelimiter.v1.CheckRequest
+	(*CheckResponse)(nil),      // 1: ratelimiter.v1.CheckResponse
+	(*BatchItem)(nil),          // 2: ratelimiter.v1.BatchItem
+	(*BatchCheckRequest)(nil),  // 3: ratelimiter.v1.BatchCheckRequest
+	(*BatchCheckResponse)(nil), // 4: ratelimiter.v1.BatchCheckResponse
+}
+var file_proto_ratelimiter_proto_depIdxs = []int32{
+	2, // 0: ratelimiter.v1.BatchCheckRequest.items:type_name -> ratelimiter.v1.BatchItem
+	1, // 1: ratelimiter.v1.BatchCheckResponse.results:type_name -> ratelimiter.v1.CheckResponse
+	0, // 2: ratelimiter.v1.RateLimiter.Check:input_type -> ratelimiter.v1.CheckRequest
+	3, // 3: ratelimiter.v1.RateLimiter.BatchCheck:input_type -> ratelimiter.v1.BatchCheckRequest
+	0, // 4: ratelimiter.v1.RateLimiter.StreamCheck:input_type -> ratelimiter.v1.CheckRequest
+	1, // 5: ratelimiter.v1.RateLimiter.Check:output_type -> ratelimiter.v1.CheckResponse
+	4, // 6: ratelimiter.v1.RateLimiter.BatchCheck:output_type -> ratelimiter.v1.BatchCheckResponse
+	1, // 7: ratelimiter.v1.RateLimiter.StreamCheck:output_type -> ratelimiter.v1.CheckResponse
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_proto_ratelimiter_proto_init() }
+func file_proto_ratelimiter_proto_init() {
+	if File_proto_ratelimiter_proto != nil {
+		return
+	}
+	file_proto_ratelimiter_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_ratelimiter_proto_rawDesc), len(file_proto_ratelimiter_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_ratelimiter_proto_goTypes,
+		DependencyIndexes: file_proto_ratelimiter_proto_depIdxs,
+		MessageInfos:      file_proto_ratelimiter_proto_msgTypes,
+	}.Build()
+	File_proto_ratelimiter_proto = out.File
+	file_proto_ratelimiter_proto_goTypes = nil
+	file_proto_ratelimiter_proto_depIdxs = nil
+}
@@ -0,0 +1,202 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proto/ratelimiter.proto
+
+package ratelimiterpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RateLimiter_Check_FullMethodName       = "/ratelimiter.v1.RateLimiter/Check"
+	RateLimiter_BatchCheck_FullMethodName  = "/ratelimiter.v1.RateLimiter/BatchCheck"
+	RateLimiter_StreamCheck_FullMethodName = "/ratelimiter.v1.RateLimiter/StreamCheck"
+)
+
+// RateLimiterClient is the client API for RateLimiter service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RateLimiter makes the same decisions as the /api/v1/limiter endpoints
+type RateLimiterClient interface {
+	// Check evaluates a single limit, or every level of a hierarchy when scope and identifier are empty
+	Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error)
+	// BatchCheck evaluates several limits in one call, like /api/v1/limiter/batch
+	BatchCheck(ctx context.Context, in *BatchCheckRequest, opts ...grpc.CallOption) (*BatchCheckResponse, error)
+	// StreamCheck answers every request sent on the stream, in order, failures are reported in the error field
+	StreamCheck(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CheckRequest, CheckResponse], error)
+}
+
+type rateLimiterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRateLimiterClient(cc grpc.ClientConnInterface) RateLimiterClient {
+	return &rateLimiterClient{cc}
+}
+
+func (c *rateLimiterClient) Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckResponse)
+	err := c.cc.Invoke(ctx, RateLimiter_Check_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterClient) BatchCheck(ctx context.Context, in *BatchCheckRequest, opts ...grpc.CallOption) (*BatchCheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchCheckResponse)
+	err := c.cc.Invoke(ctx, RateLimiter_BatchCheck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterClient) StreamCheck(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CheckRequest, CheckResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RateLimiter_ServiceDesc.Streams[0], RateLimiter_StreamCheck_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CheckRequest, CheckResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RateLimiter_StreamCheckClient = grpc.BidiStreamingClient[CheckRequest, CheckResponse]
+
+// RateLimiterServer is the server API for RateLimiter service.
+// All implementations must embed UnimplementedRateLimiterServer
+// for forward compatibility.
+//
+// RateLimiter makes the same decisions as the /api/v1/limiter endpoints
+type RateLimiterServer interface {
+	// Check evaluates a single limit, or every level of a hierarchy when scope and identifier are empty
+	Check(context.Context, *CheckRequest) (*CheckResponse, error)
+	// BatchCheck evaluates several limits in one call, like /api/v1/limiter/batch
+	BatchCheck(context.Context, *BatchCheckRequest) (*BatchCheckResponse, error)
+	// StreamCheck answers every request sent on the stream, in order, failures are reported in the error field
+	StreamCheck(grpc.BidiStreamingServer[CheckRequest, CheckResponse]) error
+	mustEmbedUnimplementedRateLimiterServer()
+}
+
+// UnimplementedRateLimiterServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRateLimiterServer struct{}
+
+func (UnimplementedRateLimiterServer) Check(context.Context, *CheckRequest) (*CheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedRateLimiterServer) BatchCheck(context.Context, *BatchCheckRequest) (*BatchCheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchCheck not implemented")
+}
+func (UnimplementedRateLimiterServer) StreamCheck(grpc.BidiStreamingServer[CheckRequest, CheckResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamCheck not implemented")
+}
+func (UnimplementedRateLimiterServer) mustEmbedUnimplementedRateLimiterServer() {}
+func (UnimplementedRateLimiterServer) testEmbeddedByValue()                     {}
+
+// UnsafeRateLimiterServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RateLimiterServer will
+// result in compilation errors.
+type UnsafeRateLimiterServer interface {
+	mustEmbedUnimplementedRateLimiterServer()
+}
+
+func RegisterRateLimiterServer(s grpc.ServiceRegistrar, srv RateLimiterServer) {
+	// If the following call pancis, it indicates UnimplementedRateLimiterServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RateLimiter_ServiceDesc, srv)
+}
+
+func _RateLimiter_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiter_Check_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServer).Check(ctx, req.(*CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiter_BatchCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServer).BatchCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiter_BatchCheck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServer).BatchCheck(ctx, req.(*BatchCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiter_StreamCheck_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RateLimiterServer).StreamCheck(&grpc.GenericServerStream[CheckRequest, CheckResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RateLimiter_StreamCheckServer = grpc.BidiStreamingServer[CheckRequest, CheckResponse]
+
+// RateLimiter_ServiceDesc is the grpc.ServiceDesc for RateLimiter service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RateLimiter_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ratelimiter.v1.RateLimiter",
+	HandlerType: (*RateLimiterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    _RateLimiter_Check_Handler,
+		},
+		{
+			MethodName: "BatchCheck",
+			Handler:    _RateLimiter_BatchCheck_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamCheck",
+			Handler:       _RateLimiter_StreamCheck_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/ratelimiter.proto",
+}
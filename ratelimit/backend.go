@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"goapp/algorithms"
+	"goapp/constants"
+	"goapp/models"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// Backend keeps the state of the limits and decides on them. The backends built by Backends run the algorithms of
+// the service in memory or on redis, any other store plugs in by implementing it
+type Backend interface {
+	// Allow charges the cost to scope:identifier when it fits the policy
+	Allow(ctx context.Context, policy *Policy, scope, identifier string, cost int64) (*Decision, error)
+	// Refund hands back the cost of a decision Allow admitted, as far as the limit still holds it
+	Refund(ctx context.Context, policy *Policy, scope, identifier string, cost int64, admitted *Decision) error
+	// Release hands back a lease of a concurrency policy, reporting false when it is unknown or expired
+	Release(ctx context.Context, policy *Policy, scope, identifier, leaseId string) (bool, error)
+}
+
+// PolicyWatcher reports the keys of the policies that changed, the policy repositories of the service are one
+type PolicyWatcher interface {
+	Watch(ctx context.Context, log zerolog.Logger, onChange func(policyKey string))
+}
+
+// Options tune the backends, the zero value keeps the in-memory defaults and does not fall back when redis fails
+type Options struct {
+	MemoryStore models.MemoryStore
+	Fallback    models.Fallback
+	// Policies, when set, has the limiters of a policy dropped as soon as it changes instead of on their next use
+	Policies PolicyWatcher
+}
+
+// Backends builds the backends running the algorithms of the service. The in-memory state of every backend it builds,
+// including the fallback of the redis ones, counts against the single key limit of its options
+type Backends struct {
+	factory *algorithms.DefaultLimiterFactory
+}
+
+// NewBackends starts sweeping idle limiters and expired in-memory keys until ctx is done
+func NewBackends(ctx context.Context, options Options) *Backends {
+	log := *zerolog.Ctx(ctx)
+
+	factory := algorithms.NewDefaultLimiterFactory(options.MemoryStore, options.Fallback, log)
+	factory.StartJanitor(ctx, log)
+	if options.Policies != nil {
+		factory.Follow(ctx, log, options.Policies)
+	}
+
+	return &Backends{factory: factory}
+}
+
+// Memory keeps the limits in the process
+func (b *Backends) Memory() Backend {
+	return &algorithmBackend{
+		factory:     b.factory,
+		limiterType: constants.ValeTypeMemory,
+	}
+}
+
+// Redis keeps the limits in redis, shared by every process using the same server, cluster or sentinel setup. The
+// scripts run through a circuit breaker of the backend, so a failing server is not waited on for every request
+func (b *Backends) Redis(rdb redis.UniversalClient) Backend {
+	return &algorithmBackend{
+		factory:     b.factory,
+		limiterType: constants.ValueTypeRedis,
+		rdb:         rdb,
+		cb:          algorithms.NewCircuitBreaker(),
+	}
+}
+
+type algorithmBackend struct {
+	factory     *algorithms.DefaultLimiterFactory
+	limiterType string
+	rdb         redis.Scripter
+	cb          *algorithms.CircuitBreaker
+}
+
+func (b *algorithmBackend) Allow(ctx context.Context, policy *Policy, scope, identifier string, cost int64) (*Decision, error) {
+	log := *zerolog.Ctx(ctx)
+
+	limiter, err := b.factory.Limiter(policy, b.limiterType, log)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := limiter.Allow(ctx, b.rdb, b.cb, log, scope, identifier, cost)
+	if err != nil {
+		return nil, err
+	}
+	return newDecision(response), nil
+}
+
+func (b *algorithmBackend) Refund(ctx context.Context, policy *Policy, scope, identifier string, cost int64, admitted *Decision) error {
+	log := *zerolog.Ctx(ctx)
+
+	limiter, err := b.factory.Limiter(policy, b.limiterType, log)
+	if err != nil {
+		return err
+	}
+
+	return algorithms.RefundCost(ctx, limiter, b.rdb, b.cb, log, scope, identifier, cost, admitted.limiterResponse())
+}
+
+func (b *algorithmBackend) Release(ctx context.Context, policy *Policy, scope, identifier, leaseId string) (bool, error) {
+	log := *zerolog.Ctx(ctx)
+
+	limiter, err := b.factory.Limiter(policy, b.limiterType, log)
+	if err != nil {
+		return false, err
+	}
+
+	return algorithms.ReleaseLease(ctx, limiter, b.rdb, b.cb, log, scope, identifier, leaseId)
+}
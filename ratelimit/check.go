@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// Check is one of the keys AllowAll charges together, each may be limited by a limiter of its own
+type Check struct {
+	Limiter *PolicyLimiter
+	Key     string
+	Cost    int64
+}
+
+// AllowAll charges the request to every key or to none. Keys are checked in order and, as soon as one rejects the
+// request or fails, the keys that admitted it are refunded and the remaining ones are not checked, so the decisions
+// stop at the key that rejected it
+func AllowAll(ctx context.Context, checks []Check) ([]*Decision, error) {
+	decisions := make([]*Decision, 0, len(checks))
+
+	for i, check := range checks {
+		decision, err := check.Limiter.Allow(ctx, check.Key, check.Cost)
+		if err != nil || !decision.Allowed {
+			refundAll(ctx, checks[:i], decisions)
+			if err != nil {
+				return nil, err
+			}
+			return append(decisions, decision), nil
+		}
+		decisions = append(decisions, decision)
+	}
+
+	return decisions, nil
+}
+
+// refundAll hands the cost back to the keys that admitted a request another key rejected
+func refundAll(ctx context.Context, checks []Check, admitted []*Decision) {
+	for i, check := range checks {
+		if err := check.Limiter.refund(ctx, check.Key, check.Cost, admitted[i]); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("key", check.Key).Msg("Error refunding the key, its cost stays charged")
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"goapp/models"
+	"time"
+)
+
+// Decision is the outcome of a check. The durations are whole seconds, rounded up
+type Decision struct {
+	Allowed bool
+	// RetryAfter is how long to wait before the same cost fits, zero when the request was admitted
+	RetryAfter time.Duration
+	// Remaining is what is left of the limit once the request was charged
+	Remaining int64
+	Limit     int64
+	// ResetAfter is how long until the whole limit is available again
+	ResetAfter time.Duration
+	// Window is the period the limit applies to, zero for the algorithms without one
+	Window time.Duration
+	// Degraded is set when the backend failed and the failure mode of the policy decided, Reason tells why
+	Degraded bool
+	Reason   string
+	// LeaseId is the lease a concurrency policy admitted the request with, handed back through PolicyLimiter.Release
+	LeaseId string
+	// Rule is the rule of a multi-rule policy and Level the level of a hierarchy that had the least room left
+	Rule  string
+	Level string
+	// PolicyVersion and Schedule are the version of the policy and the schedule that was active when it decided
+	PolicyVersion int64
+	Schedule      string
+	// ShadowDenied is set when a shadow policy admitted a request it would have rejected
+	ShadowDenied bool
+}
+
+func newDecision(response *models.LimiterResponse) *Decision {
+	return &Decision{
+		Allowed:       response.Allowed,
+		RetryAfter:    time.Duration(response.RetryAfter) * time.Second,
+		Remaining:     response.RemainingTokens,
+		Limit:         response.TotalTokens,
+		ResetAfter:    time.Duration(response.Reset) * time.Second,
+		Window:        time.Duration(response.Window) * time.Second,
+		Degraded:      response.Degraded,
+		Reason:        response.Reason,
+		LeaseId:       response.LeaseId,
+		Rule:          response.Rule,
+		Level:         response.Level,
+		PolicyVersion: response.PolicyVersion,
+		Schedule:      response.Schedule,
+		ShadowDenied:  response.ShadowDenied,
+	}
+}
+
+// limiterResponse is the decision the algorithms took, they need it back to hand its cost back
+func (d *Decision) limiterResponse() *models.LimiterResponse {
+	if d == nil {
+		return nil
+	}
+
+	return &models.LimiterResponse{
+		Allowed:         d.Allowed,
+		RetryAfter:      int64(d.RetryAfter / time.Second),
+		RemainingTokens: d.Remaining,
+		TotalTokens:     d.Limit,
+		Reset:           int64(d.ResetAfter / time.Second),
+		Window:          int64(d.Window / time.Second),
+		Degraded:        d.Degraded,
+		Reason:          d.Reason,
+		LeaseId:         d.LeaseId,
+		Rule:            d.Rule,
+		Level:           d.Level,
+		PolicyVersion:   d.PolicyVersion,
+		Schedule:        d.Schedule,
+		ShadowDenied:    d.ShadowDenied,
+	}
+}
@@ -0,0 +1,251 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapp/algorithms"
+	"goapp/constants"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrInvalidCost         = errors.New("cost must be positive")
+	ErrWaitExceedsDeadline = errors.New("waiting for the limit would exceed the context deadline")
+	ErrNoPolicy            = errors.New("no policy found")
+	ErrReleaseUnsupported  = algorithms.ErrReleaseUnsupported
+)
+
+// Level is one level of a hierarchical check, see algorithms.BuildLevels
+type Level = algorithms.Level
+
+// Limiter decides whether the cost of a request fits the limit of its key. Keys are scope:identifier, the policy
+// of the key decides which algorithm applies. Logs are written to the zerolog logger the context carries, if any
+type Limiter interface {
+	// Allow checks the request and charges its cost when it fits
+	Allow(ctx context.Context, key string, cost int64) (*Decision, error)
+	// Reserve is Allow for callers that go on to wait for a rejected reservation or hand an admitted one back
+	Reserve(ctx context.Context, key string, cost int64) (*Reservation, error)
+	// Wait blocks until the cost fits, the context is done or its deadline is too close to wait for the limit
+	Wait(ctx context.Context, key string, cost int64) (*Reservation, error)
+}
+
+// Reservation is the decision taken by Reserve or Wait
+type Reservation struct {
+	*Decision
+	cancel func(ctx context.Context) error
+}
+
+func (r *Reservation) OK() bool {
+	return r.Allowed
+}
+
+// Delay is how long to wait before the cost fits, zero when the reservation was admitted
+func (r *Reservation) Delay() time.Duration {
+	if r.Allowed {
+		return 0
+	}
+	return r.RetryAfter
+}
+
+// Cancel hands the cost of an admitted reservation back to the limit, releasing its lease under a concurrency policy.
+// Nothing is left to hand back once the window the cost was charged to has ended
+func (r *Reservation) Cancel(ctx context.Context) error {
+	if !r.Allowed || r.cancel == nil {
+		return nil
+	}
+	return r.cancel(ctx)
+}
+
+// Key joins the scope and identifier into the key the policy of scope:identifier applies to
+func Key(scope, identifier string) string {
+	return scope + ":" + identifier
+}
+
+// splitKey splits the key at the first ':', scopes cannot contain one while identifiers, ip addresses say, may
+func splitKey(key string) (string, string) {
+	scope, identifier, found := strings.Cut(key, ":")
+	if !found {
+		return "", key
+	}
+	return scope, identifier
+}
+
+// PolicyLimiter limits every key by the policy it resolves to, keeping the state in its backend
+type PolicyLimiter struct {
+	backend  Backend
+	policies PolicySource
+}
+
+func New(backend Backend, policies PolicySource) *PolicyLimiter {
+	return &PolicyLimiter{
+		backend:  backend,
+		policies: policies,
+	}
+}
+
+func (l *PolicyLimiter) policy(ctx context.Context, scope, identifier string) (*Policy, error) {
+	policy, exists := l.policies.Policy(ctx, scope, identifier)
+	if !exists {
+		return nil, fmt.Errorf("%w for scope : %s and identifier : %s", ErrNoPolicy, scope, identifier)
+	}
+	return policy, nil
+}
+
+func (l *PolicyLimiter) Allow(ctx context.Context, key string, cost int64) (*Decision, error) {
+	if cost <= 0 {
+		return nil, ErrInvalidCost
+	}
+
+	scope, identifier := splitKey(key)
+	policy, err := l.policy(ctx, scope, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.backend.Allow(ctx, policy, scope, identifier, cost)
+}
+
+// refund hands the cost of an admitted decision back, it must not be cut short by the request running out of time
+func (l *PolicyLimiter) refund(ctx context.Context, key string, cost int64, admitted *Decision) error {
+	ctx = context.WithoutCancel(ctx)
+	scope, identifier := splitKey(key)
+
+	policy, err := l.policy(ctx, scope, identifier)
+	if err != nil {
+		return err
+	}
+
+	return l.backend.Refund(ctx, policy, scope, identifier, cost, admitted)
+}
+
+// AllowLevels checks the request against every level that has a policy, with the algorithm of that policy and on
+// the keys a direct check of the level is charged to. Levels are checked from the broadest to the most specific and,
+// as soon as one rejects the request, the levels that admitted it are refunded, so it is charged to all of them or
+// none. Shadow levels are checked last and can never hold the request back
+func (l *PolicyLimiter) AllowLevels(ctx context.Context, levels []Level, cost int64) (*Decision, error) {
+	if cost <= 0 {
+		return nil, ErrInvalidCost
+	}
+
+	enforced := make([]Level, 0, len(levels))
+	shadows := make([]Level, 0)
+	for _, level := range levels {
+		policy, exists := l.policies.Policy(ctx, level.Scope, level.Identifier)
+		if !exists {
+			continue
+		}
+
+		// a lease could only be handed back for the level whose decision is returned
+		if policy.EffectiveAlgorithm() == constants.AlgorithmConcurrency {
+			return nil, fmt.Errorf("invalid policy for level %s : concurrency policies cannot be part of a hierarchy", level.Name)
+		}
+
+		if policy.Mode == constants.ModeShadow {
+			shadows = append(shadows, level)
+			continue
+		}
+		enforced = append(enforced, level)
+	}
+
+	if len(enforced) == 0 && len(shadows) == 0 {
+		return nil, fmt.Errorf("%w for any level of the hierarchy", ErrNoPolicy)
+	}
+
+	checks := make([]Check, len(enforced))
+	for i, level := range enforced {
+		checks[i] = Check{Limiter: l, Key: Key(level.Scope, level.Identifier), Cost: cost}
+	}
+
+	decisions, err := AllowAll(ctx, checks)
+	if err != nil {
+		return nil, err
+	}
+
+	// the rejecting level or, when every level admitted the request, the one with the least room left is binding
+	var response *Decision
+	for i, decision := range decisions {
+		decision.Level = enforced[i].Name
+		if response == nil || !decision.Allowed || decision.Remaining < response.Remaining {
+			response = decision
+		}
+	}
+	if response != nil && !response.Allowed {
+		zerolog.Ctx(ctx).Warn().Str("level", response.Level).Str("rule", response.Rule).Msg("Request is rejected by the hierarchy")
+		return response, nil
+	}
+
+	for _, level := range shadows {
+		decision, err := l.Allow(ctx, Key(level.Scope, level.Identifier), cost)
+		if err != nil {
+			return nil, err
+		}
+
+		if response == nil {
+			decision.Level = level.Name
+			response = decision
+		} else if decision.ShadowDenied {
+			response.ShadowDenied = true
+		}
+	}
+
+	return response, nil
+}
+
+// Release hands the lease back to the concurrency policy of the key, reporting false when it is unknown or expired
+func (l *PolicyLimiter) Release(ctx context.Context, key, leaseId string) (bool, error) {
+	scope, identifier := splitKey(key)
+
+	policy, err := l.policy(ctx, scope, identifier)
+	if err != nil {
+		return false, err
+	}
+
+	return l.backend.Release(ctx, policy, scope, identifier, leaseId)
+}
+
+func (l *PolicyLimiter) Reserve(ctx context.Context, key string, cost int64) (*Reservation, error) {
+	decision, err := l.Allow(ctx, key, cost)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reservation{
+		Decision: decision,
+		cancel: func(ctx context.Context) error {
+			return l.refund(ctx, key, cost, decision)
+		},
+	}, nil
+}
+
+func (l *PolicyLimiter) Wait(ctx context.Context, key string, cost int64) (*Reservation, error) {
+	for {
+		reservation, err := l.Reserve(ctx, key, cost)
+		if err != nil {
+			return nil, err
+		}
+		if reservation.OK() {
+			return reservation, nil
+		}
+
+		// a rejection that does not tell how long to wait, a failing backend closing the limit say, is retried
+		delay := reservation.Delay()
+		if delay <= 0 {
+			delay = constants.WaitRetryInterval
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, ErrWaitExceedsDeadline
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit_test
+
+import (
+	"context"
+	"goapp/algorithms"
+	"goapp/constants"
+	"goapp/models"
+	"goapp/ratelimit"
+	"goapp/services"
+	"goapp/store"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestAllowLevelsRollsBack(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// every level on another algorithm, each has to hand the cost back on its own
+	cache := services.NewCache(store.NewMemoryPolicyStore(
+		&models.PolicySchema{Scope: constants.LevelGlobal, Identifier: constants.GlobalIdentifier, Limit: 100, Window: "1m", Algorithm: constants.AlgorithmSlidingLog},
+		&models.PolicySchema{Scope: constants.LevelTenant, Identifier: "*", Limit: 6, Window: "1m", Algorithm: constants.AlgorithmFixedWindow},
+		&models.PolicySchema{Scope: constants.LevelUser, Identifier: "*", Limit: 3, Window: "1m", Algorithm: constants.AlgorithmGCRA},
+	))
+	cache.LoadCache(ctx, zerolog.Nop())
+	limiter := ratelimit.New(ratelimit.NewBackends(ctx, ratelimit.Options{}).Memory(), cache)
+
+	tests := []struct {
+		name         string
+		tenant, user string
+		cost         int64
+		allowed      bool
+		level        string
+		remaining    int64
+	}{
+		{name: "first request", tenant: "acme", user: "u1", cost: 1, allowed: true, level: constants.LevelUser, remaining: 2},
+		{name: "second request", tenant: "acme", user: "u1", cost: 1, allowed: true, level: constants.LevelUser, remaining: 1},
+		{name: "user used up", tenant: "acme", user: "u1", cost: 1, allowed: true, level: constants.LevelUser, remaining: 0},
+		// the global and tenant levels admitted it before the user rejected it, both are refunded
+		{name: "user rejects", tenant: "acme", user: "u1", cost: 1, allowed: false, level: constants.LevelUser, remaining: 0},
+		{name: "tenant still holds the refunded cost", tenant: "acme", user: "u2", cost: 3, allowed: true, level: constants.LevelTenant, remaining: 0},
+		{name: "tenant rejects", tenant: "acme", user: "u3", cost: 1, allowed: false, level: constants.LevelTenant, remaining: 0},
+		// users are keyed below their tenant, u1 of another tenant has a limit of its own
+		{name: "same user in another tenant", tenant: "other", user: "u1", cost: 1, allowed: true, level: constants.LevelUser, remaining: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels, err := algorithms.BuildLevels(map[string]string{constants.LevelTenant: tt.tenant, constants.LevelUser: tt.user})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decision, err := limiter.AllowLevels(ctx, levels, tt.cost)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision.Allowed != tt.allowed || decision.Level != tt.level || decision.Remaining != tt.remaining {
+				t.Fatalf("expected allowed %v by %s with %d remaining, got %+v", tt.allowed, tt.level, tt.remaining, decision)
+			}
+		})
+	}
+
+	// the global level was only charged for the admitted requests, the rejected ones were refunded
+	decision, err := limiter.Allow(ctx, ratelimit.Key(constants.LevelGlobal, constants.GlobalIdentifier), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := int64(100 - 3 - 3 - 1 - 1); decision.Remaining != expected {
+		t.Fatalf("expected %d remaining globally, got %d", expected, decision.Remaining)
+	}
+}
@@ -0,0 +1,26 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"goapp/algorithms"
+	"goapp/models"
+)
+
+type Policy = models.PolicySchema
+
+// PolicySource resolves the policy scope:identifier is limited by, the service resolves them through its policy cache
+type PolicySource interface {
+	Policy(ctx context.Context, scope, identifier string) (*Policy, bool)
+}
+
+// Policies checks the policies and resolves the keys against them, an exact policy wins over the most specific
+// matching pattern like in the policy store
+func Policies(policies ...*Policy) (PolicySource, error) {
+	for _, policy := range policies {
+		if err := algorithms.ValidatePolicy(policy); err != nil {
+			return nil, fmt.Errorf("invalid policy %s : %w", policy.Key(), err)
+		}
+	}
+	return newPolicySet(policies...), nil
+}
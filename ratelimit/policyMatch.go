@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"goapp/models"
+	"sort"
+	"strings"
+)
+
+// matchPattern matches value against a pattern where * stands for any run of characters
+func matchPattern(pattern, value string) bool {
+	parts := strings.Split(pattern, models.Wildcard)
+	if len(parts) == 1 {
+		return pattern == value
+	}
+
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(value, part)
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(part):]
+	}
+
+	return len(value) >= len(last) && strings.HasSuffix(value, last)
+}
+
+// specificity ranks how narrowly a pattern policy describes its keys, a literal scope always beats a wildcard one
+// and within the same scope the pattern with more literal characters wins, so api:premium-* beats api:* beats *:*
+func specificity(p *models.PolicySchema) int {
+	score := len(strings.ReplaceAll(p.Identifier, models.Wildcard, ""))
+	if !strings.Contains(p.Scope, models.Wildcard) {
+		score += 1 << 16
+	}
+	return score
+}
+
+// SortBySpecificity orders pattern policies from the most to the least specific, ties are broken on the key to stay deterministic
+func SortBySpecificity(patterns []*models.PolicySchema) {
+	sort.SliceStable(patterns, func(i, j int) bool {
+		si, sj := specificity(patterns[i]), specificity(patterns[j])
+		if si != sj {
+			return si > sj
+		}
+		return patterns[i].Scope+":"+patterns[i].Identifier < patterns[j].Scope+":"+patterns[j].Identifier
+	})
+}
+
+// MatchPolicy returns the most specific pattern policy matching scope:identifier
+func MatchPolicy(patterns []*models.PolicySchema, scope, identifier string) (*models.PolicySchema, bool) {
+	for _, policy := range patterns {
+		if matchPattern(policy.Scope, scope) && matchPattern(policy.Identifier, identifier) {
+			return policy, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"context"
+	"goapp/models"
+	"time"
+)
+
+// policySet resolves policies from a fixed list the same way the policy cache of the service does, an exact policy
+// winning over the most specific matching pattern, it is meant for limiters embedded together with their policies
+type policySet struct {
+	exact    map[string]*models.PolicySchema
+	patterns []*models.PolicySchema
+}
+
+func newPolicySet(policies ...*models.PolicySchema) *policySet {
+	set := &policySet{
+		exact:    make(map[string]*models.PolicySchema, len(policies)),
+		patterns: make([]*models.PolicySchema, 0),
+	}
+
+	for _, policy := range policies {
+		if policy.IsPattern() {
+			set.patterns = append(set.patterns, policy)
+			continue
+		}
+		set.exact[policy.Key()] = policy
+	}
+
+	SortBySpecificity(set.patterns)
+	return set
+}
+
+func (s *policySet) Policy(ctx context.Context, scope, identifier string) (*models.PolicySchema, bool) {
+	if policy, ok := s.exact[scope+":"+identifier]; ok {
+		return policy.ActiveAt(time.Now()), true
+	}
+
+	if pattern, matched := MatchPolicy(s.patterns, scope, identifier); matched {
+		return pattern.ActiveAt(time.Now()), true
+	}
+	return nil, false
+}
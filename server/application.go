@@ -2,18 +2,24 @@ package server
 
 import (
 	"context"
-	"goapp/algorithms"
+	"fmt"
 	"goapp/constants"
+	"goapp/grpcserver"
 	"goapp/logger"
+	"goapp/logic"
+	"goapp/ratelimit"
 	"goapp/services"
 	"goapp/store"
 	"goapp/utils"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 )
 
 type Application struct {
@@ -22,11 +28,13 @@ type Application struct {
 	config    *utils.Config
 	log       zerolog.Logger
 	db        *store.Db
+	policies  store.PolicyRepository
 	rdb       *redis.Client
 	cache     *services.Cache
-	factory   algorithms.LimiterFactory
-	cb        *services.CircuitBreaker
+	limiters  logic.Limiters
 	logCloser func()
+
+	forwardAuth []*logic.ForwardAuthRule
 }
 
 func NewApplication(filePath string) (*Application, error) {
@@ -43,20 +51,44 @@ func NewApplication(filePath string) (*Application, error) {
 		return nil, err
 	}
 
-	// Initialize the database
-	db, _ := config.Database.InitDb(context.Background(), log, config.Queries)
+	// the forward-auth rules are checked up front, a broken rule would otherwise fail every request
+	forwardAuth, err := logic.NewForwardAuthRules(config.ForwardAuth)
+	if err != nil {
+		log.Error().Err(err).Msg("Error reading the forward-auth rules")
+		cancel()
+		return nil, err
+	}
+
+	switch config.RateLimitHeaders {
+	case "", constants.RateLimitHeadersLegacy, constants.RateLimitHeadersIetf, constants.RateLimitHeadersBoth:
+	default:
+		err := fmt.Errorf("unsupported rate limit headers: %s", config.RateLimitHeaders)
+		log.Error().Err(err).Msg("Error reading the rate limit headers")
+		cancel()
+		return nil, err
+	}
+
+	// Initialize the policy store, only the postgres one needs the database
+	db, policies, err := initPolicyStore(config, log)
+	if err != nil {
+		log.Error().Err(err).Msg("Error initializing the policy store")
+		cancel()
+		return nil, err
+	}
 
 	// Initialize Redis
 	rdb := store.InitRedis(&config.Redis, log)
 
-	// creating the defautl limiter factory
-	factory := &algorithms.DefaultLimiterFactory{}
-
 	// create the cache variable
-	cache := services.NewCache()
-
-	// Initializing circuit breaker
-	cb := services.NewCircuitBreaker()
+	cache := services.NewCache(policies)
+
+	// a limiter for each limiter type a request can ask for, both resolve their policies through the cache and share
+	// the in-memory key limit, limiter instances live until they go idle or their policy changes
+	backends := ratelimit.NewBackends(log.WithContext(ctx), ratelimit.Options{MemoryStore: config.MemoryStore, Fallback: config.Fallback, Policies: policies})
+	limiters := logic.Limiters{
+		constants.ValueTypeRedis: ratelimit.New(backends.Redis(rdb), cache),
+		constants.ValeTypeMemory: ratelimit.New(backends.Memory(), cache),
+	}
 
 	return &Application{
 		ctx:       ctx,
@@ -64,23 +96,59 @@ func NewApplication(filePath string) (*Application, error) {
 		config:    config,
 		log:       log,
 		db:        db,
+		policies:  policies,
 		rdb:       rdb,
 		cache:     cache,
-		factory:   factory,
-		cb:        cb,
+		limiters:  limiters,
 		logCloser: logCloser,
+
+		forwardAuth: forwardAuth,
 	}, nil
 }
 
+// initPolicyStore picks where policies are read from, a nil database means policies cannot be managed through the api
+func initPolicyStore(config *utils.Config, log zerolog.Logger) (*store.Db, store.PolicyRepository, error) {
+	switch config.PolicyStore.Type {
+	case "", constants.PolicyStorePostgres:
+		db, err := config.Database.InitDb(context.Background(), log, config.Queries)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, store.NewPostgresPolicyStore(db, config.PolicyNotifications.Channel), nil
+	case constants.PolicyStoreFile:
+		interval := constants.DefaultPolicyPollInterval
+		if config.PolicyStore.PollInterval != "" {
+			parsed, err := time.ParseDuration(config.PolicyStore.PollInterval)
+			if err != nil || parsed <= 0 {
+				return nil, nil, fmt.Errorf("invalid policy store poll interval %q", config.PolicyStore.PollInterval)
+			}
+			interval = parsed
+		}
+
+		policies, err := store.NewFilePolicyStore(config.PolicyStore.File, interval, logic.ValidatePolicies)
+		return nil, policies, err
+	}
+
+	return nil, nil, fmt.Errorf("unknown policy store type: %s", config.PolicyStore.Type)
+}
+
 func (app *Application) StartServer() error {
 	initCtx, initCancel := context.WithTimeout(app.ctx, constants.ContextTimeout)
 	defer initCancel()
 
-	// create the tables
-	app.db.CreateTables(initCtx, app.log, app.config.Tables)
+	if app.db != nil {
+		// create the tables
+		app.db.CreateTables(initCtx, app.log, app.config.Tables)
+
+		// the trigger behind the policy change notifications
+		app.db.RunStatements(initCtx, app.log, app.config.PolicyNotifications.Setup)
+	}
 
 	// Load the cache
-	app.cache.LoadCache(initCtx, app.log, app.db, app.config.Queries.Fetch.FetchPolicies)
+	app.cache.LoadCache(initCtx, app.log)
+
+	// evict policies changed by any instance instead of waiting for them to expire
+	app.cache.ListenForChanges(app.ctx, app.log)
 
 	// Start fiber server
 	app.StartFiberServer()
@@ -92,27 +160,63 @@ func (app *Application) StartFiberServer() {
 	appServer := app.SetupRoutes()
 
 	// using the channel to handle the graceful shutdown
-	listenErr := make(chan error)
+	listenErr := make(chan error, 2)
 	go func() {
 		listenErr <- appServer.Listen(app.config.Ports.FiberServer)
 	}()
 
 	app.log.Info().Msg("Server started listening at port : " + app.config.Ports.FiberServer)
 
+	// the grpc server shares the limiters with the http api and is skipped when no port is configured
+	var grpcServer *grpc.Server
+	if app.config.Ports.GrpcServer != "" {
+		listener, err := net.Listen("tcp", app.config.Ports.GrpcServer)
+		if err != nil {
+			listenErr <- err
+		} else {
+			grpcServer = grpcserver.NewServer(app.log, app.rdb, app.limiters, app.cache)
+			go func() {
+				listenErr <- grpcServer.Serve(listener)
+			}()
+
+			app.log.Info().Msg("gRPC server started listening at port : " + app.config.Ports.GrpcServer)
+		}
+	}
+
 	// waiting for the interrupt signal for graceful shutdowning the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 	select {
 	case err := <-listenErr:
-		app.log.Error().Err(err).Msg("Error starting fiber server")
+		app.log.Error().Err(err).Msg("Error starting the server")
 	case <-quit:
 		app.log.Info().Msg("Graceful shutdown initiated")
 		// gracefully shutting down every dependencies
 		app.cancel()
 		appServer.Shutdown()
-		app.db.Db.Close()
+		if grpcServer != nil {
+			stopGrpcServer(grpcServer)
+		}
+		if app.db != nil {
+			app.db.Db.Close()
+		}
 		app.rdb.Close()
 		app.logCloser()
 	}
 }
+
+// stopGrpcServer lets the running calls finish, streams that stay open past the timeout are cut
+func stopGrpcServer(grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(constants.ContextTimeout):
+		grpcServer.Stop()
+	}
+}
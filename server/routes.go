@@ -2,6 +2,7 @@ package server
 
 import (
 	"goapp/handlers"
+	"goapp/store"
 
 	"github.com/gofiber/adaptor/v2"
 	"github.com/gofiber/fiber/v2"
@@ -13,10 +14,30 @@ func (app *Application) SetupRoutes() *fiber.App {
 	appServer := fiber.New()
 	appServer.Use(requestid.New())
 
-	configHandler := handlers.NewConfigHandler(app.ctx, app.config, app.log, app.db, app.rdb, app.factory, app.cache, app.cb)
+	// the policy api needs a repository keeping the history of its writes, a policy file is edited instead
+	managed, _ := app.policies.(store.VersionedPolicyRepository)
+
+	configHandler := handlers.NewConfigHandler(app.ctx, app.config, app.log, managed, app.rdb, app.limiters, app.cache, app.forwardAuth)
 
 	// Defining the routes
 	appServer.Get("/api/v1/limiter", configHandler.GetLimiter)
+	appServer.Post("/api/v1/limiter", configHandler.GetLimiter)
+	appServer.Post("/api/v1/limiter/release", configHandler.ReleaseLease)
+	appServer.Post("/api/v1/limiter/batch", configHandler.BatchLimiter)
+
+	// proxies keep the method of the original request on the auth subrequest
+	appServer.All("/api/v1/limiter/forward-auth", configHandler.ForwardAuth)
+
+	// policy management, validation works with any policy store
+	appServer.Post("/api/v1/policies/validate", configHandler.ValidatePolicy)
+
+	policies := appServer.Group("/api/v1/policies", configHandler.RequirePolicyDatabase)
+	policies.Get("", configHandler.GetPolicies)
+	policies.Post("", configHandler.CreatePolicy)
+	policies.Put("", configHandler.UpdatePolicy)
+	policies.Delete("", configHandler.DeletePolicy)
+	policies.Get("/versions", configHandler.GetPolicyVersions)
+	policies.Post("/rollback", configHandler.RollbackPolicy)
 
 	appServer.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 
@@ -2,59 +2,156 @@ package services
 
 import (
 	"context"
+	"errors"
 	"goapp/constants"
+	"goapp/models"
+	"goapp/ratelimit"
 	"goapp/store"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/dgraph-io/ristretto"
 	"github.com/rs/zerolog"
 )
 
-type PolicySchema struct {
-	Scope      string `json:"scope"`
-	Identifier string `json:"identifier"`
-	Limit      int    `json:"limit"`
-	Window     string `json:"window"`
-	Burst      int    `json:"burst"`
-	Algorithm  string `json:"algorithm"`
-}
+// missingPolicy is cached for keys no policy applies to, so unknown identifiers do not hit the database on every request
+type missingPolicy struct{}
 
 type Cache struct {
-	data *ristretto.Cache
+	data     *ristretto.Cache
+	policies store.PolicyRepository
+
+	// pattern policies are matched in memory, ordered from the most to the least specific
+	mu               sync.RWMutex
+	patterns         []*models.PolicySchema
+	patternsLoadedAt time.Time
 }
 
-func NewCache() *Cache {
+func NewCache(policies store.PolicyRepository) *Cache {
 	c, _ := ristretto.NewCache(&ristretto.Config{
 		NumCounters: 1e6,
 		MaxCost:     1 << 28,
 		BufferItems: 64,
 	})
 	return &Cache{
-		data: c,
+		data:     c,
+		policies: policies,
 	}
 }
 
-func (c *Cache) LoadCache(ctx context.Context, log zerolog.Logger, db *store.Db, query string) {
-	policies := FetchPolicies(ctx, db, log, query)
+func (c *Cache) LoadCache(ctx context.Context, log zerolog.Logger) {
+	policies, err := c.policies.List(ctx, log)
+	if err != nil {
+		log.Error().Err(err).Msg("Error loading the policies, they are read on first use instead")
+		return
+	}
 
+	patterns := make([]*models.PolicySchema, 0)
 	for policyKey, policy := range policies {
+		if policy.IsPattern() {
+			patterns = append(patterns, policy)
+			continue
+		}
 		c.data.SetWithTTL(policyKey, policy, 1, constants.PolicyCacheDuration)
 	}
+
+	c.setPatterns(patterns)
+}
+
+func (c *Cache) setPatterns(patterns []*models.PolicySchema) {
+	ratelimit.SortBySpecificity(patterns)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.patterns = patterns
+	c.patternsLoadedAt = time.Now()
+}
+
+// getPatterns returns the pattern policies, reloading them once they are older than the policy cache duration
+func (c *Cache) getPatterns(ctx context.Context, log zerolog.Logger) []*models.PolicySchema {
+	c.mu.RLock()
+	patterns, loadedAt := c.patterns, c.patternsLoadedAt
+	c.mu.RUnlock()
+
+	if time.Since(loadedAt) < constants.PolicyCacheDuration {
+		return patterns
+	}
+
+	fetched, err := c.policies.Patterns(ctx, log)
+	if err != nil {
+		return patterns
+	}
+
+	c.setPatterns(fetched)
+	return fetched
 }
 
-func (c *Cache) GetPolicy(ctx context.Context, db *store.Db, log zerolog.Logger, scope, identifier, query string) (*PolicySchema, bool) {
+// ListenForChanges evicts cached policies as soon as the policy store reports them changed
+func (c *Cache) ListenForChanges(ctx context.Context, log zerolog.Logger) {
+	c.policies.Watch(ctx, log, c.InvalidateKey)
+}
+
+// Invalidate drops the cached policy after it was written
+func (c *Cache) Invalidate(policy *models.PolicySchema) {
+	c.InvalidateKey(policy.Key())
+}
+
+// InvalidateKey drops the policy cached under the key, a changed pattern may have been resolved for any key
+// so the whole cache is dropped instead, as it is for an empty key
+func (c *Cache) InvalidateKey(policyKey string) {
+	if policyKey != "" && !strings.Contains(policyKey, models.Wildcard) {
+		c.data.Del(policyKey)
+		return
+	}
+
+	c.InvalidateAll()
+}
+
+// InvalidateAll drops every cached policy, the patterns are reloaded on the next miss
+func (c *Cache) InvalidateAll() {
+	c.mu.Lock()
+	c.patternsLoadedAt = time.Time{}
+	c.mu.Unlock()
+
+	c.data.Clear()
+}
+
+// GetPolicy resolves the policy for scope:identifier, an exact policy wins over the most specific matching pattern
+// and the result, including the absence of any policy, is cached under the exact key. Schedules are applied on
+// every lookup so that the limits change at the scheduled time and not when the cache entry expires
+func (c *Cache) GetPolicy(ctx context.Context, log zerolog.Logger, scope, identifier string) (*models.PolicySchema, bool) {
 	cacheKey := scope + ":" + identifier
 
 	if val, found := c.data.Get(cacheKey); found {
-		if cachedPolicy, ok := val.(*PolicySchema); ok {
-			return cachedPolicy, true
+		switch cachedPolicy := val.(type) {
+		case *models.PolicySchema:
+			return cachedPolicy.ActiveAt(time.Now()), true
+		case missingPolicy:
+			return nil, false
 		}
 	}
 
-	dbPolicy, exists := FetchPolicyByKey(ctx, db, log, query, cacheKey)
-	if exists && dbPolicy != nil {
-		c.data.SetWithTTL(cacheKey, dbPolicy, 1, constants.PolicyCacheDuration)
-		return dbPolicy, true
+	storedPolicy, err := c.policies.Get(ctx, log, cacheKey)
+	if err == nil {
+		c.data.SetWithTTL(cacheKey, storedPolicy, 1, constants.PolicyCacheDuration)
+		return storedPolicy.ActiveAt(time.Now()), true
+	}
+	if !errors.Is(err, store.ErrPolicyNotFound) {
+		// the store is failing, remembering the key as missing would hide its policy once the store is back
+		return nil, false
 	}
 
+	if pattern, matched := ratelimit.MatchPolicy(c.getPatterns(ctx, log), scope, identifier); matched {
+		c.data.SetWithTTL(cacheKey, pattern, 1, constants.PolicyCacheDuration)
+		return pattern.ActiveAt(time.Now()), true
+	}
+
+	c.data.SetWithTTL(cacheKey, missingPolicy{}, 1, constants.MissingPolicyCacheDuration)
 	return nil, false
 }
+
+// Policy resolves the policy like GetPolicy, logging to the logger the context carries
+func (c *Cache) Policy(ctx context.Context, scope, identifier string) (*models.PolicySchema, bool) {
+	return c.GetPolicy(ctx, *zerolog.Ctx(ctx), scope, identifier)
+}
@@ -2,9 +2,11 @@ package services
 
 import (
 	"context"
+	"goapp/models"
+
 	"github.com/redis/go-redis/v9"
 )
 
-func ExecuteLuaScript(ctx context.Context, rdb *redis.Client, keys []string, policy *PolicySchema) {
+func ExecuteLuaScript(ctx context.Context, rdb *redis.Client, keys []string, policy *models.PolicySchema) {
 
-}
\ No newline at end of file
+}
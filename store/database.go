@@ -33,7 +33,8 @@ func (dbCreds *Database) InitDb(ctx context.Context, log zerolog.Logger, queries
 	dbCreds.once.Do(func() {
 		databaseUrl := fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%s sslmode=disable", dbCreds.Username, dbCreds.Password, dbCreds.DatabaseName, dbCreds.Host, dbCreds.Port)
 
-		dbConfig, err := pgxpool.ParseConfig(databaseUrl)
+		var dbConfig *pgxpool.Config
+		dbConfig, err = pgxpool.ParseConfig(databaseUrl)
 		if err != nil {
 			err = fmt.Errorf("Error while initiating the database pool : %w", err)
 			return
@@ -52,14 +53,21 @@ func (dbCreds *Database) InitDb(ctx context.Context, log zerolog.Logger, queries
 		}
 	})
 
+	// the pool is only created once, and not at all when the config is broken
+	if pool == nil {
+		if err == nil {
+			err = fmt.Errorf("Database pool was already initiated")
+		}
+		return nil, err
+	}
+
 	// ping the database to check the connection
 	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		log.Error().Err(err).Msg("Database connection ping failed")
 		return nil, fmt.Errorf("Database connection ping failed : %w", err)
 	}
 
-
-
 	return &Db{
 		Db:      pool,
 		Queries: queries,
@@ -78,3 +86,12 @@ func (db *Db) CreateTables(ctx context.Context, log zerolog.Logger, tables map[s
 		}
 	}
 }
+
+// RunStatements executes the statements in order, used for the objects that depend on the tables
+func (db *Db) RunStatements(ctx context.Context, log zerolog.Logger, statements []string) {
+	for _, statement := range statements {
+		if _, err := db.Db.Exec(ctx, statement); err != nil {
+			log.Error().Err(err).Str("statement", statement).Msg("Error running the setup statement")
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"goapp/models"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"sigs.k8s.io/yaml"
+)
+
+// FilePolicyStore serves the policies of a yaml or json policy file and reloads it when the file changes,
+// for deployments that run without Postgres
+type FilePolicyStore struct {
+	path     string
+	interval time.Duration
+	validate func(policies []*models.PolicySchema) error
+
+	mu       sync.RWMutex
+	policies map[string]*models.PolicySchema
+	modTime  time.Time
+	size     int64
+}
+
+// NewFilePolicyStore fails when the file does not hold policies that validate accepts. The check is injected since
+// the policy rules belong to the algorithms, which depend on this package
+func NewFilePolicyStore(path string, interval time.Duration, validate func(policies []*models.PolicySchema) error) (*FilePolicyStore, error) {
+	fs := &FilePolicyStore{
+		path:     path,
+		interval: interval,
+		validate: validate,
+	}
+
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// ReadPolicyFile parses a policy file, json is valid yaml so one decoder covers both formats
+func ReadPolicyFile(path string) ([]*models.PolicySchema, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policyFile models.PolicyFile
+	if err := yaml.UnmarshalStrict(content, &policyFile); err != nil {
+		return nil, fmt.Errorf("error parsing %s : %w", path, err)
+	}
+	return policyFile.Policies, nil
+}
+
+// load replaces the policies with the ones of the file, all of them or none. A file that is rejected is still
+// remembered so that it is not read again until it changes
+func (fs *FilePolicyStore) load() error {
+	info, err := os.Stat(fs.path)
+	if err != nil {
+		return err
+	}
+
+	loaded, err := fs.read()
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.modTime = info.ModTime()
+	fs.size = info.Size()
+	if err != nil {
+		return err
+	}
+
+	fs.policies = loaded
+	return nil
+}
+
+func (fs *FilePolicyStore) read() (map[string]*models.PolicySchema, error) {
+	policies, err := ReadPolicyFile(fs.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fs.validate(policies); err != nil {
+		return nil, fmt.Errorf("invalid policies in %s : %w", fs.path, err)
+	}
+
+	loaded := make(map[string]*models.PolicySchema, len(policies))
+	for _, policy := range policies {
+		loaded[policy.Key()] = policy
+	}
+	return loaded, nil
+}
+
+func (fs *FilePolicyStore) changed() bool {
+	info, err := os.Stat(fs.path)
+	if err != nil {
+		return false
+	}
+
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return !info.ModTime().Equal(fs.modTime) || info.Size() != fs.size
+}
+
+func (fs *FilePolicyStore) List(ctx context.Context, log zerolog.Logger) (map[string]*models.PolicySchema, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	policies := make(map[string]*models.PolicySchema, len(fs.policies))
+	for key, policy := range fs.policies {
+		policies[key] = policy
+	}
+	return policies, nil
+}
+
+func (fs *FilePolicyStore) Patterns(ctx context.Context, log zerolog.Logger) ([]*models.PolicySchema, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	patterns := make([]*models.PolicySchema, 0)
+	for key, policy := range fs.policies {
+		if strings.Contains(key, models.Wildcard) {
+			patterns = append(patterns, policy)
+		}
+	}
+	return patterns, nil
+}
+
+func (fs *FilePolicyStore) Get(ctx context.Context, log zerolog.Logger, policyKey string) (*models.PolicySchema, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	policy, ok := fs.policies[policyKey]
+	if !ok {
+		return nil, ErrPolicyNotFound
+	}
+	return policy, nil
+}
+
+// Upsert is not supported, the policy file is the source of truth and is edited instead
+func (fs *FilePolicyStore) Upsert(ctx context.Context, log zerolog.Logger, policy *models.PolicySchema) error {
+	return ErrReadOnly
+}
+
+func (fs *FilePolicyStore) Delete(ctx context.Context, log zerolog.Logger, policyKey string) error {
+	return ErrReadOnly
+}
+
+// Watch polls the file and reloads it when it changed, a file that does not parse or validate keeps the previous policies
+func (fs *FilePolicyStore) Watch(ctx context.Context, log zerolog.Logger, onChange func(policyKey string)) {
+	go func() {
+		ticker := time.NewTicker(fs.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !fs.changed() {
+					continue
+				}
+
+				if err := fs.load(); err != nil {
+					log.Error().Err(err).Str("file", fs.path).Msg("Error reloading the policy file, keeping the previous policies")
+					continue
+				}
+
+				log.Info().Str("file", fs.path).Msg("Policy file changed, reloaded the policies")
+				onChange("")
+			}
+		}
+	}()
+}
@@ -0,0 +1,118 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"goapp/logic"
+	"goapp/store"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func writePolicyFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the store notices changes through the modification time, which may not move between two quick writes
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const validPolicies = `policies:
+  - scope: api
+    identifier: client
+    limit: 5
+    window: 1m
+    algorithm: fixed_window
+`
+
+func TestFilePolicyStoreValidatesPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"fixed window without a window", "policies:\n  - scope: api\n    identifier: client\n    limit: 5\n    window: 0s\n    algorithm: fixed_window\n"},
+		{"unknown algorithm", "policies:\n  - scope: api\n    identifier: client\n    limit: 5\n    algorithm: unknown\n"},
+		{"duplicate key", validPolicies + "  - scope: api\n    identifier: client\n    limit: 1\n    window: 1m\n    algorithm: fixed_window\n"},
+		{"unparsable file", "policies: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policies.yaml")
+			writePolicyFile(t, path, tt.content, time.Now())
+
+			if _, err := store.NewFilePolicyStore(path, time.Hour, logic.ValidatePolicies); err == nil {
+				t.Fatal("expected the store to refuse the file")
+			}
+		})
+	}
+}
+
+func TestFilePolicyStoreKeepsPoliciesOnInvalidReload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	start := time.Now().Add(-time.Hour)
+	writePolicyFile(t, path, validPolicies, start)
+
+	policies, err := store.NewFilePolicyStore(path, 10*time.Millisecond, logic.ValidatePolicies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan string, 10)
+	policies.Watch(ctx, zerolog.Nop(), func(policyKey string) { changes <- policyKey })
+
+	// the second policy is valid but the first is not, so neither of them is loaded
+	writePolicyFile(t, path, `policies:
+  - scope: api
+    identifier: client
+    limit: 5
+    window: 0s
+    algorithm: fixed_window
+  - scope: api
+    identifier: other
+    limit: 5
+    window: 1m
+    algorithm: fixed_window
+`, start.Add(time.Minute))
+
+	select {
+	case <-changes:
+		t.Fatal("an invalid file was reloaded")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	policy, err := policies.Get(ctx, zerolog.Nop(), "api:client")
+	if err != nil || policy.Window != "1m" {
+		t.Fatalf("expected the previous policy to stay in use, got %+v, %v", policy, err)
+	}
+	if _, err := policies.Get(ctx, zerolog.Nop(), "api:other"); !errors.Is(err, store.ErrPolicyNotFound) {
+		t.Fatalf("expected no policy of the rejected file to be loaded, got %v", err)
+	}
+
+	// fixing the file loads it again
+	writePolicyFile(t, path, validPolicies+"  - scope: api\n    identifier: other\n    limit: 1\n    window: 1m\n    algorithm: fixed_window\n", start.Add(2*time.Minute))
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("the fixed file was not reloaded")
+	}
+	if _, err := policies.Get(ctx, zerolog.Nop(), "api:other"); err != nil {
+		t.Fatalf("expected the fixed file to be loaded, got %v", err)
+	}
+
+	if err := policies.Upsert(ctx, zerolog.Nop(), nil); !errors.Is(err, store.ErrReadOnly) {
+		t.Fatalf("expected the file store to be read only, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"goapp/models"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// MemoryPolicyStore keeps the policies in a map, it lets the whole decision path run without a database
+type MemoryPolicyStore struct {
+	mu       sync.RWMutex
+	policies map[string]*models.PolicySchema
+	watchers map[int]func(policyKey string)
+	nextId   int
+}
+
+func NewMemoryPolicyStore(policies ...*models.PolicySchema) *MemoryPolicyStore {
+	ms := &MemoryPolicyStore{
+		policies: make(map[string]*models.PolicySchema, len(policies)),
+		watchers: make(map[int]func(policyKey string)),
+	}
+
+	for _, policy := range policies {
+		ms.policies[policy.Key()] = policy
+	}
+	return ms
+}
+
+func (ms *MemoryPolicyStore) List(ctx context.Context, log zerolog.Logger) (map[string]*models.PolicySchema, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	policies := make(map[string]*models.PolicySchema, len(ms.policies))
+	for key, policy := range ms.policies {
+		policies[key] = policy
+	}
+	return policies, nil
+}
+
+func (ms *MemoryPolicyStore) Patterns(ctx context.Context, log zerolog.Logger) ([]*models.PolicySchema, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	patterns := make([]*models.PolicySchema, 0)
+	for key, policy := range ms.policies {
+		if strings.Contains(key, models.Wildcard) {
+			patterns = append(patterns, policy)
+		}
+	}
+	return patterns, nil
+}
+
+func (ms *MemoryPolicyStore) Get(ctx context.Context, log zerolog.Logger, policyKey string) (*models.PolicySchema, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	policy, ok := ms.policies[policyKey]
+	if !ok {
+		return nil, ErrPolicyNotFound
+	}
+	return policy, nil
+}
+
+// Upsert creates the policy or replaces the one stored under its key, the watchers learn about it before it returns
+func (ms *MemoryPolicyStore) Upsert(ctx context.Context, log zerolog.Logger, policy *models.PolicySchema) error {
+	// stored as a copy so that the caller changing its policy afterwards does not bypass the watchers
+	stored := *policy
+
+	ms.mu.Lock()
+	ms.policies[stored.Key()] = &stored
+	ms.mu.Unlock()
+
+	ms.notify(stored.Key())
+	return nil
+}
+
+// Delete removes the policy stored under the key, ErrPolicyNotFound when there is none
+func (ms *MemoryPolicyStore) Delete(ctx context.Context, log zerolog.Logger, policyKey string) error {
+	ms.mu.Lock()
+	_, ok := ms.policies[policyKey]
+	delete(ms.policies, policyKey)
+	ms.mu.Unlock()
+
+	if !ok {
+		return ErrPolicyNotFound
+	}
+
+	ms.notify(policyKey)
+	return nil
+}
+
+// Watch registers onChange until the context is done, it is called synchronously by the writes so a policy
+// written to the store is never served stale from the cache afterwards
+func (ms *MemoryPolicyStore) Watch(ctx context.Context, log zerolog.Logger, onChange func(policyKey string)) {
+	ms.mu.Lock()
+	id := ms.nextId
+	ms.nextId++
+	ms.watchers[id] = onChange
+	ms.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		ms.mu.Lock()
+		delete(ms.watchers, id)
+		ms.mu.Unlock()
+	}()
+}
+
+func (ms *MemoryPolicyStore) notify(policyKey string) {
+	ms.mu.RLock()
+	watchers := make([]func(string), 0, len(ms.watchers))
+	for _, onChange := range ms.watchers {
+		watchers = append(watchers, onChange)
+	}
+	ms.mu.RUnlock()
+
+	for _, onChange := range watchers {
+		onChange(policyKey)
+	}
+}
@@ -0,0 +1,121 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"goapp/algorithms"
+	"goapp/constants"
+	"goapp/models"
+	"goapp/ratelimit"
+	"goapp/services"
+	"goapp/store"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func fixedWindow(scope, identifier string, limit int) *models.PolicySchema {
+	return &models.PolicySchema{
+		Scope:      scope,
+		Identifier: identifier,
+		Limit:      limit,
+		Window:     "1m",
+		Algorithm:  constants.AlgorithmFixedWindow,
+	}
+}
+
+// newLimiter runs the decision path of the service in memory, from the cache through the limiter factory
+func newLimiter(t *testing.T, policies *store.MemoryPolicyStore) *ratelimit.PolicyLimiter {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cache := services.NewCache(policies)
+	cache.LoadCache(ctx, zerolog.Nop())
+	cache.ListenForChanges(ctx, zerolog.Nop())
+
+	backends := ratelimit.NewBackends(ctx, ratelimit.Options{})
+	return ratelimit.New(backends.Memory(), cache)
+}
+
+func expectDecisions(t *testing.T, limiter *ratelimit.PolicyLimiter, key string, expected ...bool) {
+	t.Helper()
+
+	for i, allowed := range expected {
+		decision, err := limiter.Allow(context.Background(), key, 1)
+		if err != nil {
+			t.Fatalf("request %d on %s failed : %v", i, key, err)
+		}
+		if decision.Allowed != allowed {
+			t.Fatalf("request %d on %s : expected allowed to be %v, got %+v", i, key, allowed, decision)
+		}
+	}
+}
+
+func TestMemoryPolicyStoreFactoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	log := zerolog.Nop()
+
+	cache := services.NewCache(store.NewMemoryPolicyStore(fixedWindow("api", "client", 2)))
+	factory := algorithms.NewDefaultLimiterFactory(models.MemoryStore{}, models.Fallback{}, log)
+
+	policy, exists := cache.GetPolicy(ctx, log, "api", "client")
+	if !exists {
+		t.Fatal("expected the policy of api:client to be found")
+	}
+
+	limiter, err := factory.Limiter(policy, constants.ValeTypeMemory, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range []bool{true, true, false} {
+		decision, err := limiter.Allow(ctx, nil, nil, log, "api", "client", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decision.Allowed != expected {
+			t.Fatalf("request %d : expected allowed to be %v, got %+v", i, expected, decision)
+		}
+	}
+}
+
+func TestMemoryPolicyStoreAllowsAndDenies(t *testing.T) {
+	limiter := newLimiter(t, store.NewMemoryPolicyStore(
+		fixedWindow("api", "client", 2),
+		fixedWindow("api", "*", 1),
+	))
+
+	// the exact policy wins over the pattern, which limits every other key on its own
+	expectDecisions(t, limiter, "api:client", true, true, false)
+	expectDecisions(t, limiter, "api:first", true, false)
+	expectDecisions(t, limiter, "api:second", true, false)
+
+	if _, err := limiter.Allow(context.Background(), "web:client", 1); !errors.Is(err, ratelimit.ErrNoPolicy) {
+		t.Fatalf("expected ErrNoPolicy for a key without a policy, got %v", err)
+	}
+}
+
+func TestMemoryPolicyStoreWritesReachTheCache(t *testing.T) {
+	ctx := context.Background()
+	policies := store.NewMemoryPolicyStore(fixedWindow("api", "client", 1))
+	limiter := newLimiter(t, policies)
+
+	expectDecisions(t, limiter, "api:client", true, false)
+
+	// the next request sees the new policy, whose limiter starts over with the keys it limits in memory
+	if err := policies.Upsert(ctx, zerolog.Nop(), fixedWindow("api", "client", 3)); err != nil {
+		t.Fatal(err)
+	}
+	expectDecisions(t, limiter, "api:client", true, true, true, false)
+
+	if err := policies.Delete(ctx, zerolog.Nop(), "api:client"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Allow(ctx, "api:client", 1); !errors.Is(err, ratelimit.ErrNoPolicy) {
+		t.Fatalf("expected ErrNoPolicy once the policy is deleted, got %v", err)
+	}
+
+	if err := policies.Delete(ctx, zerolog.Nop(), "api:client"); !errors.Is(err, store.ErrPolicyNotFound) {
+		t.Fatalf("expected ErrPolicyNotFound deleting a missing policy, got %v", err)
+	}
+}
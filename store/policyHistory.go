@@ -0,0 +1,381 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapp/constants"
+	"goapp/models"
+	"reflect"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrVersionNotFound      = errors.New("policy version not found")
+	ErrVersionNotRestorable = errors.New("policy version records a deletion and cannot be restored")
+	ErrVersionInvalid       = errors.New("policy version is not a valid policy anymore")
+)
+
+// PolicyRecord is a stored policy together with the time it was last written, used for optimistic concurrency
+type PolicyRecord struct {
+	models.PolicySchema
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FieldChange is the old and new value of a policy field, nil when the field did not exist
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// PolicyVersion is one entry of the history of a policy, deletions carry no policy
+type PolicyVersion struct {
+	Version   int64                  `json:"version"`
+	Action    string                 `json:"action"`
+	Policy    *models.PolicySchema   `json:"policy"`
+	Diff      map[string]FieldChange `json:"diff"`
+	ChangedBy string                 `json:"changedBy"`
+	ChangedAt time.Time              `json:"changedAt"`
+}
+
+// DiffPolicies lists the fields that differ between two versions, the version number itself is left out
+func DiffPolicies(from, to *models.PolicySchema) map[string]FieldChange {
+	before, after := policyFields(from), policyFields(to)
+
+	diff := make(map[string]FieldChange)
+	for field, value := range after {
+		if old, ok := before[field]; !ok || !reflect.DeepEqual(old, value) {
+			diff[field] = FieldChange{From: before[field], To: value}
+		}
+	}
+	for field, value := range before {
+		if _, ok := after[field]; !ok {
+			diff[field] = FieldChange{From: value}
+		}
+	}
+
+	delete(diff, "version")
+	return diff
+}
+
+func policyFields(policy *models.PolicySchema) map[string]any {
+	fields := make(map[string]any)
+	if policy == nil {
+		return fields
+	}
+
+	raw, err := sonic.Marshal(policy)
+	if err != nil {
+		return fields
+	}
+	_ = sonic.Unmarshal(raw, &fields)
+	return fields
+}
+
+func (ps *PostgresPolicyStore) Records(ctx context.Context, log zerolog.Logger) ([]*PolicyRecord, error) {
+	rows, err := ps.db.Db.Query(ctx, ps.db.Queries.Fetch.FetchPolicyRecords)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching policy records from the database")
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	records := make([]*PolicyRecord, 0)
+	for rows.Next() {
+		record := &PolicyRecord{}
+		var policy *models.PolicySchema
+		if err := rows.Scan(&policy, &record.UpdatedAt); err != nil {
+			log.Error().Err(err).Msg("Error scanning policy record from the database")
+			return nil, err
+		}
+
+		record.PolicySchema = *policy
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+func (ps *PostgresPolicyStore) Record(ctx context.Context, log zerolog.Logger, policyKey string) (*PolicyRecord, error) {
+	return scanPolicyRecord(ps.db.Db.QueryRow(ctx, ps.db.Queries.Fetch.FetchPolicyRecord, policyKey), log)
+}
+
+func scanPolicyRecord(row pgx.Row, log zerolog.Logger) (*PolicyRecord, error) {
+	record := &PolicyRecord{}
+	var policy *models.PolicySchema
+
+	err := row.Scan(&policy, &record.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPolicyNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching policy record from the database")
+		return nil, err
+	}
+
+	record.PolicySchema = *policy
+	return record, nil
+}
+
+// nextVersion takes the next version of the key from its counter row, which stays locked until the transaction ends.
+// Writers of the same key wait for each other even when its policy row does not exist, deleted keys included, so two
+// of them never record the same version
+func (ps *PostgresPolicyStore) nextVersion(ctx context.Context, tx pgx.Tx, policyKey string) (int64, error) {
+	var version int64
+	err := tx.QueryRow(ctx, ps.db.Queries.History.NextPolicyVersion, policyKey).Scan(&version)
+	return version, err
+}
+
+func (ps *PostgresPolicyStore) recordVersion(ctx context.Context, tx pgx.Tx, policyKey string, change *PolicyVersion) error {
+	_, err := tx.Exec(ctx, ps.db.Queries.History.InsertPolicyVersion, policyKey, change.Version, change.Action, change.Policy, change.Diff, change.ChangedBy)
+	return err
+}
+
+// lockPolicy reads the current policy and keeps it locked until the transaction ends
+func (ps *PostgresPolicyStore) lockPolicy(ctx context.Context, tx pgx.Tx, log zerolog.Logger, policyKey string) (*PolicyRecord, error) {
+	return scanPolicyRecord(tx.QueryRow(ctx, ps.db.Queries.Modify.LockPolicy, policyKey), log)
+}
+
+// Upsert writes the policy as the next version of its key, recorded under the repository actor
+func (ps *PostgresPolicyStore) Upsert(ctx context.Context, log zerolog.Logger, policy *models.PolicySchema) error {
+	err := pgx.BeginFunc(ctx, ps.db.Db, func(tx pgx.Tx) error {
+		current, err := ps.lockPolicy(ctx, tx, log, policy.Key())
+		if errors.Is(err, ErrPolicyNotFound) {
+			_, err = ps.insertPolicy(ctx, tx, policy, constants.RepositoryActor)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = ps.updatePolicy(ctx, tx, &current.PolicySchema, policy, constants.RepositoryActor)
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Error upserting the policy in the database")
+	}
+
+	return err
+}
+
+// Delete removes the policy whatever its version, recorded under the repository actor
+func (ps *PostgresPolicyStore) Delete(ctx context.Context, log zerolog.Logger, policyKey string) error {
+	return ps.Remove(ctx, log, policyKey, nil, constants.RepositoryActor)
+}
+
+// Create stores a new policy as the next version of its key
+func (ps *PostgresPolicyStore) Create(ctx context.Context, log zerolog.Logger, policy *models.PolicySchema, actor string) (time.Time, error) {
+	var updatedAt time.Time
+
+	err := pgx.BeginFunc(ctx, ps.db.Db, func(tx pgx.Tx) error {
+		var err error
+		updatedAt, err = ps.insertPolicy(ctx, tx, policy, actor)
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrPolicyExists) {
+		log.Error().Err(err).Msg("Error inserting policy into the database")
+	}
+
+	return updatedAt, err
+}
+
+// Update only writes the policy when it has not changed since expectedUpdatedAt
+func (ps *PostgresPolicyStore) Update(ctx context.Context, log zerolog.Logger, policy *models.PolicySchema, expectedUpdatedAt time.Time, actor string) (time.Time, error) {
+	var updatedAt time.Time
+
+	err := pgx.BeginFunc(ctx, ps.db.Db, func(tx pgx.Tx) error {
+		current, err := ps.lockPolicy(ctx, tx, log, policy.Key())
+		if err != nil {
+			return err
+		}
+		if !current.UpdatedAt.Equal(expectedUpdatedAt) {
+			return ErrPolicyConflict
+		}
+
+		updatedAt, err = ps.updatePolicy(ctx, tx, &current.PolicySchema, policy, actor)
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrPolicyConflict) && !errors.Is(err, ErrPolicyNotFound) {
+		log.Error().Err(err).Msg("Error updating policy in the database")
+	}
+
+	return updatedAt, err
+}
+
+func (ps *PostgresPolicyStore) Remove(ctx context.Context, log zerolog.Logger, policyKey string, expectedUpdatedAt *time.Time, actor string) error {
+	err := pgx.BeginFunc(ctx, ps.db.Db, func(tx pgx.Tx) error {
+		current, err := ps.lockPolicy(ctx, tx, log, policyKey)
+		if err != nil {
+			return err
+		}
+		if expectedUpdatedAt != nil && !current.UpdatedAt.Equal(*expectedUpdatedAt) {
+			return ErrPolicyConflict
+		}
+
+		return ps.deletePolicy(ctx, tx, &current.PolicySchema, actor)
+	})
+	if err != nil && !errors.Is(err, ErrPolicyConflict) && !errors.Is(err, ErrPolicyNotFound) {
+		log.Error().Err(err).Msg("Error deleting policy from the database")
+	}
+
+	return err
+}
+
+func (ps *PostgresPolicyStore) insertPolicy(ctx context.Context, tx pgx.Tx, policy *models.PolicySchema, actor string) (time.Time, error) {
+	var updatedAt time.Time
+
+	version, err := ps.nextVersion(ctx, tx, policy.Key())
+	if err != nil {
+		return updatedAt, err
+	}
+	policy.Version = version
+
+	err = tx.QueryRow(ctx, ps.db.Queries.Modify.InsertPolicy, policy.Key(), policy).Scan(&updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return updatedAt, ErrPolicyExists
+	}
+	if err != nil {
+		return updatedAt, err
+	}
+
+	return updatedAt, ps.recordVersion(ctx, tx, policy.Key(), &PolicyVersion{
+		Version:   version,
+		Action:    constants.PolicyActionCreate,
+		Policy:    policy,
+		Diff:      DiffPolicies(nil, policy),
+		ChangedBy: actor,
+	})
+}
+
+func (ps *PostgresPolicyStore) updatePolicy(ctx context.Context, tx pgx.Tx, current, policy *models.PolicySchema, actor string) (time.Time, error) {
+	var updatedAt time.Time
+
+	version, err := ps.nextVersion(ctx, tx, policy.Key())
+	if err != nil {
+		return updatedAt, err
+	}
+	policy.Version = version
+
+	if err := tx.QueryRow(ctx, ps.db.Queries.Modify.UpdatePolicy, policy.Key(), policy).Scan(&updatedAt); err != nil {
+		return updatedAt, err
+	}
+
+	return updatedAt, ps.recordVersion(ctx, tx, policy.Key(), &PolicyVersion{
+		Version:   version,
+		Action:    constants.PolicyActionUpdate,
+		Policy:    policy,
+		Diff:      DiffPolicies(current, policy),
+		ChangedBy: actor,
+	})
+}
+
+func (ps *PostgresPolicyStore) deletePolicy(ctx context.Context, tx pgx.Tx, current *models.PolicySchema, actor string) error {
+	version, err := ps.nextVersion(ctx, tx, current.Key())
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, ps.db.Queries.Modify.DeletePolicy, current.Key()); err != nil {
+		return err
+	}
+
+	return ps.recordVersion(ctx, tx, current.Key(), &PolicyVersion{
+		Version:   version,
+		Action:    constants.PolicyActionDelete,
+		Diff:      DiffPolicies(current, nil),
+		ChangedBy: actor,
+	})
+}
+
+// Rollback makes an earlier version the active policy again, recorded as a new version so history is never rewritten.
+// The version is checked by validate before it is written, it may have been stored under rules that no longer hold
+func (ps *PostgresPolicyStore) Rollback(ctx context.Context, log zerolog.Logger, policyKey string, version int64, actor string, validate func(*models.PolicySchema) error) (*PolicyRecord, error) {
+	record := &PolicyRecord{}
+
+	err := pgx.BeginFunc(ctx, ps.db.Db, func(tx pgx.Tx) error {
+		target, err := scanPolicyVersion(tx.QueryRow(ctx, ps.db.Queries.History.FetchPolicyVersion, policyKey, version))
+		if err != nil {
+			return err
+		}
+		if target.Policy == nil {
+			return ErrVersionNotRestorable
+		}
+		if err := validate(target.Policy); err != nil {
+			return fmt.Errorf("%w : %w", ErrVersionInvalid, err)
+		}
+
+		// the policy may have been deleted since, in which case the rollback recreates it
+		var currentPolicy *models.PolicySchema
+		current, err := ps.lockPolicy(ctx, tx, log, policyKey)
+		if err == nil {
+			currentPolicy = &current.PolicySchema
+		} else if !errors.Is(err, ErrPolicyNotFound) {
+			return err
+		}
+
+		next, err := ps.nextVersion(ctx, tx, policyKey)
+		if err != nil {
+			return err
+		}
+
+		record.PolicySchema = *target.Policy
+		record.Version = next
+
+		if err := tx.QueryRow(ctx, ps.db.Queries.Modify.UpsertPolicy, policyKey, &record.PolicySchema).Scan(&record.UpdatedAt); err != nil {
+			return err
+		}
+
+		return ps.recordVersion(ctx, tx, policyKey, &PolicyVersion{
+			Version:   next,
+			Action:    constants.PolicyActionRollback,
+			Policy:    &record.PolicySchema,
+			Diff:      DiffPolicies(currentPolicy, &record.PolicySchema),
+			ChangedBy: actor,
+		})
+	})
+	if err != nil {
+		if !errors.Is(err, ErrVersionNotFound) && !errors.Is(err, ErrVersionNotRestorable) && !errors.Is(err, ErrVersionInvalid) {
+			log.Error().Err(err).Msg("Error rolling back the policy")
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (ps *PostgresPolicyStore) Versions(ctx context.Context, log zerolog.Logger, policyKey string) ([]*PolicyVersion, error) {
+	rows, err := ps.db.Db.Query(ctx, ps.db.Queries.History.FetchPolicyVersions, policyKey)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching policy versions from the database")
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	versions := make([]*PolicyVersion, 0)
+	for rows.Next() {
+		version, err := scanPolicyVersion(rows)
+		if err != nil {
+			log.Error().Err(err).Msg("Error scanning policy version from the database")
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+func scanPolicyVersion(row pgx.Row) (*PolicyVersion, error) {
+	version := &PolicyVersion{}
+
+	err := row.Scan(&version.Version, &version.Action, &version.Policy, &version.Diff, &version.ChangedBy, &version.ChangedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVersionNotFound
+	}
+	return version, err
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"goapp/constants"
+	"goapp/models"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// PolicyChange is one step of bringing the stored policies in line with the desired ones
+type PolicyChange struct {
+	Action  string                 `json:"action"`
+	Key     string                 `json:"key"`
+	Diff    map[string]FieldChange `json:"diff"`
+	current *models.PolicySchema
+	desired *models.PolicySchema
+}
+
+// PlanPolicies lists the changes turning the current policies into the desired ones ordered by key,
+// policies missing from desired are only deleted when prune is set
+func PlanPolicies(desired []*models.PolicySchema, current map[string]*models.PolicySchema, prune bool) []*PolicyChange {
+	changes := make([]*PolicyChange, 0)
+	wanted := make(map[string]bool, len(desired))
+
+	for _, policy := range desired {
+		wanted[policy.Key()] = true
+
+		existing, ok := current[policy.Key()]
+		if !ok {
+			changes = append(changes, &PolicyChange{Action: constants.PolicyActionCreate, Key: policy.Key(), Diff: DiffPolicies(nil, policy), desired: policy})
+			continue
+		}
+
+		if diff := DiffPolicies(existing, policy); len(diff) > 0 {
+			changes = append(changes, &PolicyChange{Action: constants.PolicyActionUpdate, Key: policy.Key(), Diff: diff, current: existing, desired: policy})
+		}
+	}
+
+	if prune {
+		for key, existing := range current {
+			if !wanted[key] {
+				changes = append(changes, &PolicyChange{Action: constants.PolicyActionDelete, Key: key, Diff: DiffPolicies(existing, nil), current: existing})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// Plan plans the sync of the desired policies against the stored ones without applying it
+func (ps *PostgresPolicyStore) Plan(ctx context.Context, log zerolog.Logger, desired []*models.PolicySchema, prune bool) ([]*PolicyChange, error) {
+	current, err := fetchAllPolicies(ctx, ps.db.Db, ps.db.Queries.Fetch.FetchPolicies)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching the stored policies")
+		return nil, err
+	}
+
+	return PlanPolicies(desired, current, prune), nil
+}
+
+// Sync applies the plan for the desired policies in a single transaction, other writers are held off
+// until it commits so the plan cannot go stale while it is applied
+func (ps *PostgresPolicyStore) Sync(ctx context.Context, log zerolog.Logger, desired []*models.PolicySchema, prune bool, actor string) ([]*PolicyChange, error) {
+	var changes []*PolicyChange
+
+	err := pgx.BeginFunc(ctx, ps.db.Db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, ps.db.Queries.Modify.LockPolicies); err != nil {
+			return err
+		}
+
+		current, err := fetchAllPolicies(ctx, tx, ps.db.Queries.Fetch.FetchPolicies)
+		if err != nil {
+			return err
+		}
+
+		changes = PlanPolicies(desired, current, prune)
+		for _, change := range changes {
+			switch change.Action {
+			case constants.PolicyActionCreate:
+				_, err = ps.insertPolicy(ctx, tx, change.desired, actor)
+			case constants.PolicyActionUpdate:
+				_, err = ps.updatePolicy(ctx, tx, change.current, change.desired, actor)
+			case constants.PolicyActionDelete:
+				err = ps.deletePolicy(ctx, tx, change.current, actor)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Error syncing the policies, nothing was applied")
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// querier is what the pool and a transaction have in common
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// fetchAllPolicies reads every stored policy, unlike List it fails instead of skipping what it cannot read
+func fetchAllPolicies(ctx context.Context, q querier, query string) (map[string]*models.PolicySchema, error) {
+	rows, err := q.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	policies := make(map[string]*models.PolicySchema)
+	for rows.Next() {
+		var policy *models.PolicySchema
+		if err := rows.Scan(&policy); err != nil {
+			return nil, err
+		}
+		policies[policy.Key()] = policy
+	}
+
+	return policies, rows.Err()
+}
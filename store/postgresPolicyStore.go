@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"goapp/constants"
+	"goapp/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// PostgresPolicyStore reads the rateLimitPolicies table and learns about changes through the channel
+// its trigger notifies on
+type PostgresPolicyStore struct {
+	db      *Db
+	channel string
+}
+
+func NewPostgresPolicyStore(db *Db, channel string) *PostgresPolicyStore {
+	return &PostgresPolicyStore{
+		db:      db,
+		channel: channel,
+	}
+}
+
+func (ps *PostgresPolicyStore) List(ctx context.Context, log zerolog.Logger) (map[string]*models.PolicySchema, error) {
+	return ps.fetch(ctx, log, ps.db.Queries.Fetch.FetchPolicies)
+}
+
+func (ps *PostgresPolicyStore) Patterns(ctx context.Context, log zerolog.Logger) ([]*models.PolicySchema, error) {
+	fetched, err := ps.fetch(ctx, log, ps.db.Queries.Fetch.FetchPatternPolicies)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]*models.PolicySchema, 0, len(fetched))
+	for _, policy := range fetched {
+		patterns = append(patterns, policy)
+	}
+	return patterns, nil
+}
+
+func (ps *PostgresPolicyStore) fetch(ctx context.Context, log zerolog.Logger, query string) (map[string]*models.PolicySchema, error) {
+	rows, err := ps.db.Db.Query(ctx, query)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching policies from the database")
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	data := make(map[string]*models.PolicySchema)
+	for rows.Next() {
+		var policy *models.PolicySchema
+		if err := rows.Scan(&policy); err != nil {
+			log.Error().Err(err).Msg("Error scanning policy from the database")
+			continue
+		}
+
+		data[policy.Key()] = policy
+	}
+
+	return data, rows.Err()
+}
+
+func (ps *PostgresPolicyStore) Get(ctx context.Context, log zerolog.Logger, policyKey string) (*models.PolicySchema, error) {
+	var policy *models.PolicySchema
+	err := ps.db.Db.QueryRow(ctx, ps.db.Queries.Fetch.FetchPolicyByKey, policyKey).Scan(&policy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// not an error, the key may still be covered by a pattern policy
+		return nil, ErrPolicyNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching policy by key from the database")
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// Watch listens on the channel the rateLimitPolicies trigger sends the policy key to on every insert, update and delete
+func (ps *PostgresPolicyStore) Watch(ctx context.Context, log zerolog.Logger, onChange func(policyKey string)) {
+	if ps.channel == "" {
+		log.Warn().Msg("Policy change notifications are disabled, cached policies expire after the cache duration")
+		return
+	}
+
+	go func() {
+		backoff := constants.PolicyListenRetryInterval
+		for {
+			listening, err := ps.listen(ctx, log, onChange)
+			if ctx.Err() != nil {
+				return
+			}
+
+			// a connection that worked for a while starts over with a short retry
+			if listening {
+				backoff = constants.PolicyListenRetryInterval
+			}
+			log.Error().Err(err).Dur("retryIn", backoff).Msg("Lost the policy change notifications, reconnecting")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, constants.PolicyListenMaxRetryInterval)
+		}
+	}()
+}
+
+// listen holds a connection subscribed to the channel until it fails, it reports whether the subscription succeeded
+func (ps *PostgresPolicyStore) listen(ctx context.Context, log zerolog.Logger, onChange func(policyKey string)) (bool, error) {
+	pooled, err := ps.db.Db.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// the connection is taken out of the pool so that listening does not hold one of its slots forever
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{ps.channel}.Sanitize()); err != nil {
+		return false, err
+	}
+
+	// notifications sent while we were not listening are lost, so nothing seen before can be trusted
+	onChange("")
+	log.Info().Str("channel", ps.channel).Msg("Listening for policy changes")
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		log.Debug().Str("policyKey", notification.Payload).Msg("Policy changed")
+		onChange(notification.Payload)
+	}
+}
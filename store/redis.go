@@ -57,6 +57,8 @@ func InitRedis(redisDetails *RedisConfig, log zerolog.Logger) *redis.Client {
 		Addr: redisDetails.Host + ":" + redisDetails.Port,
 	})
 
+	// added first so that batched commands are only measured once, as the pipeline they are sent in
+	rdb.AddHook(pipelineHook{})
 	rdb.AddHook(metricsHook{})
 
 	return rdb
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type pipelineBatchKey struct{}
+
+type queuedCmd struct {
+	cmd  redis.Cmder
+	done chan error
+}
+
+// PipelineBatch collects the commands sent by limiters evaluated concurrently for one batch request, once every
+// limiter still running is waiting on redis their commands are sent in a single pipeline
+type PipelineBatch struct {
+	ctx     context.Context
+	rdb     *redis.Client
+	mu      sync.Mutex
+	running int
+	queued  []queuedCmd
+}
+
+// NewPipelineBatch returns a context that routes the redis commands of the given number of concurrent limiters
+// through the batch, each of them has to call Done once it returns
+func NewPipelineBatch(ctx context.Context, rdb *redis.Client, size int) (context.Context, *PipelineBatch) {
+	batch := &PipelineBatch{
+		ctx:     ctx,
+		rdb:     rdb,
+		running: size,
+	}
+	return context.WithValue(ctx, pipelineBatchKey{}, batch), batch
+}
+
+func (b *PipelineBatch) Done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.running--
+	b.flushIfReady()
+}
+
+func (b *PipelineBatch) enqueue(ctx context.Context, cmd redis.Cmder) error {
+	queued := queuedCmd{cmd: cmd, done: make(chan error, 1)}
+
+	b.mu.Lock()
+	b.queued = append(b.queued, queued)
+	b.flushIfReady()
+	b.mu.Unlock()
+
+	select {
+	case err := <-queued.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flushIfReady sends the queued commands when nobody else can add to them, callers hold the lock
+func (b *PipelineBatch) flushIfReady() {
+	if len(b.queued) == 0 || len(b.queued) < b.running {
+		return
+	}
+
+	queued := b.queued
+	b.queued = nil
+	go b.exec(queued)
+}
+
+func (b *PipelineBatch) exec(queued []queuedCmd) {
+	// every command carries its own error, the one returned for the whole pipeline adds nothing
+	_, _ = b.rdb.Pipelined(b.ctx, func(pipe redis.Pipeliner) error {
+		for _, q := range queued {
+			pipe.Process(b.ctx, q.cmd)
+		}
+		return nil
+	})
+
+	for _, q := range queued {
+		q.done <- q.cmd.Err()
+	}
+}
+
+// pipelineHook hands the commands issued under a batch context to the batch instead of sending them one by one
+type pipelineHook struct{}
+
+func (pipelineHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (pipelineHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if batch, ok := ctx.Value(pipelineBatchKey{}).(*PipelineBatch); ok {
+			return batch.enqueue(ctx, cmd)
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (pipelineHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
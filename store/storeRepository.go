@@ -1,12 +1,72 @@
 package store
 
-type LimiterRepository interface {
-	// FetchPolicies() map[string]*services.PolicySchema
-	// FetchPolicyByKey(key string) (*services.PolicySchema, bool)
+import (
+	"context"
+	"errors"
+	"goapp/models"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrPolicyNotFound = errors.New("policy not found")
+	ErrPolicyExists   = errors.New("policy already exists")
+	ErrPolicyConflict = errors.New("policy was modified since it was read")
+	ErrReadOnly       = errors.New("policy repository is read only")
+)
+
+// PolicyRepository is where the limiter reads its policies from and where they are written to
+type PolicyRepository interface {
+	// List returns every policy keyed by scope:identifier
+	List(ctx context.Context, log zerolog.Logger) (map[string]*models.PolicySchema, error)
+
+	// Patterns returns the policies whose key contains a wildcard
+	Patterns(ctx context.Context, log zerolog.Logger) ([]*models.PolicySchema, error)
+
+	// Get returns the policy stored under the exact key, ErrPolicyNotFound when there is none
+	Get(ctx context.Context, log zerolog.Logger, policyKey string) (*models.PolicySchema, error)
+
+	// Upsert creates the policy or replaces the one stored under its key, ErrReadOnly when the repository is not written to
+	Upsert(ctx context.Context, log zerolog.Logger, policy *models.PolicySchema) error
+
+	// Delete removes the policy stored under the key, ErrPolicyNotFound when there is none
+	Delete(ctx context.Context, log zerolog.Logger, policyKey string) error
+
+	// Watch calls onChange with the key of every policy that changed until the context is done,
+	// an empty key means that any policy may have changed
+	Watch(ctx context.Context, log zerolog.Logger, onChange func(policyKey string))
 }
 
-// type StoreStruct struct {
-// 	db  *pgxpool.Pool
-// 	rdb *redis.Client
-// 	log zerolog.Logger
-// }
+// VersionedPolicyRepository keeps the history of every write and checks the writes of the policy api against
+// concurrent edits, the policy api and cli need one
+type VersionedPolicyRepository interface {
+	PolicyRepository
+
+	// Records returns every policy together with the time it was last written
+	Records(ctx context.Context, log zerolog.Logger) ([]*PolicyRecord, error)
+
+	// Record returns the policy stored under the key together with the time it was last written
+	Record(ctx context.Context, log zerolog.Logger, policyKey string) (*PolicyRecord, error)
+
+	// Create stores a new policy, ErrPolicyExists when its key is taken
+	Create(ctx context.Context, log zerolog.Logger, policy *models.PolicySchema, actor string) (time.Time, error)
+
+	// Update replaces the policy as long as it was last written at expectedUpdatedAt, ErrPolicyConflict otherwise
+	Update(ctx context.Context, log zerolog.Logger, policy *models.PolicySchema, expectedUpdatedAt time.Time, actor string) (time.Time, error)
+
+	// Remove deletes the policy, a nil expectedUpdatedAt deletes it whatever its version
+	Remove(ctx context.Context, log zerolog.Logger, policyKey string, expectedUpdatedAt *time.Time, actor string) error
+
+	// Versions returns the history of the key, newest first
+	Versions(ctx context.Context, log zerolog.Logger, policyKey string) ([]*PolicyVersion, error)
+
+	// Rollback makes an earlier version the active policy again once validate accepts it
+	Rollback(ctx context.Context, log zerolog.Logger, policyKey string, version int64, actor string, validate func(*models.PolicySchema) error) (*PolicyRecord, error)
+
+	// Plan lists the changes Sync would make without applying them
+	Plan(ctx context.Context, log zerolog.Logger, desired []*models.PolicySchema, prune bool) ([]*PolicyChange, error)
+
+	// Sync makes the stored policies match the desired ones in a single step, nothing is applied when it fails
+	Sync(ctx context.Context, log zerolog.Logger, desired []*models.PolicySchema, prune bool, actor string) ([]*PolicyChange, error)
+}
@@ -9,13 +9,19 @@ import (
 )
 
 type Config struct {
-	Ports      models.Ports      `json:"ports"`
-	Database   store.Database    `json:"database"`
-	Redis      store.RedisConfig `json:"redis"`
-	Tables     map[string]string `json:"tables"`
-	Queries    models.Queries    `json:"queries"`
-	MaxTokens  float64           `json:"maxTokens"`
-	RefillRate float64           `json:"refillRate"`
+	Ports               models.Ports               `json:"ports"`
+	Database            store.Database             `json:"database"`
+	Redis               store.RedisConfig          `json:"redis"`
+	Tables              map[string]string          `json:"tables"`
+	Queries             models.Queries             `json:"queries"`
+	MemoryStore         models.MemoryStore         `json:"memoryStore"`
+	Fallback            models.Fallback            `json:"fallback"`
+	PolicyStore         models.PolicyStoreConfig   `json:"policyStore"`
+	PolicyNotifications models.PolicyNotifications `json:"policyNotifications"`
+	ForwardAuth         models.ForwardAuth         `json:"forwardAuth"`
+	RateLimitHeaders    string                     `json:"rateLimitHeaders"`
+	MaxTokens           float64                    `json:"maxTokens"`
+	RefillRate          float64                    `json:"refillRate"`
 }
 
 func (config *Config) LoadConfig(filePath string) error {